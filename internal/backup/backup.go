@@ -0,0 +1,226 @@
+// Package backup keeps a local history of schema snapshots, taken before
+// commands change the server so they can be undone.
+//
+// Each snapshot is a YAML file named after its number, when it was taken
+// and the command it was taken for, like
+// 000042_20261018T150405Z_remove-rack.yaml. Only the newest are kept. In git
+// mode the directory is also a Git repository with a commit per snapshot,
+// which keeps the full history.
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Modes are the backup settings of a context. Auto takes a snapshot before
+// every change, git also commits it and off takes them only on request.
+var Modes = []string{"auto", "git", "off"}
+
+// DefaultKeep is the number of snapshots kept when not configured.
+const DefaultKeep = 20
+
+const timeFormat = "20060102T150405Z"
+
+// Store is the snapshot history of one context.
+type Store struct {
+	dir  string
+	mode string
+	keep int
+}
+
+// Snapshot is one saved schema.
+type Snapshot struct {
+	ID      int
+	Time    time.Time
+	Command string // the command it was taken before, like "remove rack"
+	Path    string
+}
+
+// New returns the Store in dir for a mode, "" for auto, keeping the newest
+// keep snapshots, or DefaultKeep if keep is 0.
+func New(dir, mode string, keep int) (*Store, error) {
+	if mode == "" {
+		mode = "auto"
+	}
+
+	if !slices.Contains(Modes, mode) {
+		return nil, fmt.Errorf("unknown backup mode %q, want one of %s", mode, strings.Join(Modes, ", "))
+	}
+
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+
+	return &Store{dir: dir, mode: mode, keep: keep}, nil
+}
+
+// Auto reports whether snapshots are taken before every change.
+func (s *Store) Auto() bool {
+	return s.mode != "off"
+}
+
+// Save stores a schema as the newest snapshot and removes the oldest beyond
+// the number kept. A schema the same as the newest snapshot is not saved
+// again, the newest is returned instead.
+func (s *Store) Save(data []byte, command string) (*Snapshot, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := Snapshot{
+		ID:      1,
+		Time:    time.Now().UTC().Truncate(time.Second),
+		Command: command,
+	}
+
+	if len(snaps) > 0 {
+		if b, err := os.ReadFile(snaps[0].Path); err == nil && bytes.Equal(b, data) {
+			return &snaps[0], nil
+		}
+
+		snap.ID = snaps[0].ID + 1
+	}
+
+	name := fmt.Sprintf("%06d_%s_%s.yaml", snap.ID, snap.Time.Format(timeFormat), strings.ReplaceAll(command, " ", "-"))
+	snap.Path = filepath.Join(s.dir, name)
+
+	if err := os.WriteFile(snap.Path, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	snaps = append([]Snapshot{snap}, snaps...)
+
+	for _, old := range snaps[min(s.keep, len(snaps)):] {
+		if err := os.Remove(old.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.mode == "git" {
+		if err := s.commit(fmt.Sprintf("Backup %d before %s", snap.ID, command)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &snap, nil
+}
+
+// List returns the snapshots, newest first.
+func (s *Store) List() ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []Snapshot
+
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".yaml")
+		if !ok || e.IsDir() {
+			continue
+		}
+
+		parts := strings.SplitN(name, "_", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+
+		t, err := time.Parse(timeFormat, parts[1])
+		if err != nil {
+			continue
+		}
+
+		snaps = append(snaps, Snapshot{
+			ID:      id,
+			Time:    t,
+			Command: strings.ReplaceAll(parts[2], "-", " "),
+			Path:    filepath.Join(s.dir, e.Name()),
+		})
+	}
+
+	slices.SortFunc(snaps, func(a, b Snapshot) int {
+		return b.ID - a.ID
+	})
+
+	return snaps, nil
+}
+
+// Get returns a snapshot by ID, or the newest for 0.
+func (s *Store) Get(id int) (*Snapshot, error) {
+	snaps, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no backups in %s", s.dir)
+	}
+
+	if id == 0 {
+		return &snaps[0], nil
+	}
+
+	i := slices.IndexFunc(snaps, func(snap Snapshot) bool {
+		return snap.ID == id
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("backup %d not found", id)
+	}
+
+	return &snaps[i], nil
+}
+
+// commit commits everything in the directory, creating the repository the
+// first time.
+func (s *Store) commit(msg string) error {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); errors.Is(err, fs.ErrNotExist) {
+		if err := s.git("init", "--quiet"); err != nil {
+			return err
+		}
+	}
+
+	if err := s.git("add", "--all"); err != nil {
+		return err
+	}
+
+	return s.git("-c", "user.name=stack", "-c", "user.email=stack@localhost",
+		"commit", "--quiet", "--message", msg)
+}
+
+func (s *Store) git(args ...string) error {
+	var out bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", s.dir}, args...)...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(out.String()))
+	}
+
+	return nil
+}
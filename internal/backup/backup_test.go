@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestSave saves changing schemas into a store keeping three, which numbers
+// them on from the newest, drops the oldest and does not save a schema the
+// same as the newest again.
+func TestSave(t *testing.T) {
+	s, err := New(t.TempDir(), "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, data := range []string{"a", "b", "c", "d"} {
+		snap, err := s.Save([]byte(data), "set host")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if snap.ID != i+1 {
+			t.Errorf("%s: got ID %d, want %d", data, snap.ID, i+1)
+		}
+	}
+
+	snap, err := s.Save([]byte("d"), "remove rack")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.ID != 4 || snap.Command != "set host" {
+		t.Errorf("saved the same schema again as %+v", snap)
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+
+	for _, snap := range snaps {
+		ids = append(ids, snap.ID)
+	}
+
+	if want := []int{4, 3, 2}; !slices.Equal(ids, want) {
+		t.Fatalf("got IDs %v, want %v", ids, want)
+	}
+
+	if b, err := os.ReadFile(snaps[2].Path); err != nil || string(b) != "b" {
+		t.Errorf("got %q, %v for backup 2, want b", b, err)
+	}
+}
+
+// TestList reads the snapshot file names, newest first, skipping anything
+// else in the directory.
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"000002_20261018T150405Z_remove-rack.yaml",
+		"000010_20261019T080000Z_load.yaml",
+		"000003_notatime_load.yaml",
+		"x_20261018T150405Z_load.yaml",
+		"000004_20261018T150405Z.yaml",
+		"000005_20261018T150405Z_load.json",
+		".git",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "000006_20261018T150405Z_load.yaml"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(dir, "off", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Snapshot{
+		{
+			ID:      10,
+			Time:    time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+			Command: "load",
+			Path:    filepath.Join(dir, "000010_20261019T080000Z_load.yaml"),
+		},
+		{
+			ID:      2,
+			Time:    time.Date(2026, 10, 18, 15, 4, 5, 0, time.UTC),
+			Command: "remove rack",
+			Path:    filepath.Join(dir, "000002_20261018T150405Z_remove-rack.yaml"),
+		},
+	}
+
+	if !slices.EqualFunc(snaps, want, func(a, b Snapshot) bool {
+		return a.ID == b.ID && a.Time.Equal(b.Time) && a.Command == b.Command && a.Path == b.Path
+	}) {
+		t.Errorf("got %+v, want %+v", snaps, want)
+	}
+}
+
+func TestGet(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "missing"), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get(0); err == nil {
+		t.Error("got a backup from an empty store")
+	}
+
+	for _, data := range []string{"a", "b"} {
+		if _, err := s.Save([]byte(data), "load"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if snap, err := s.Get(0); err != nil || snap.ID != 2 {
+		t.Errorf("got %+v, %v for the newest, want backup 2", snap, err)
+	}
+
+	if snap, err := s.Get(1); err != nil || snap.ID != 1 {
+		t.Errorf("got %+v, %v, want backup 1", snap, err)
+	}
+
+	if _, err := s.Get(3); err == nil {
+		t.Error("got backup 3, which does not exist")
+	}
+}
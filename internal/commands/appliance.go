@@ -4,23 +4,27 @@ import (
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
-	"endobit.io/table"
 
 	"endobit.io/metal-cli/internal/flags"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
 )
 
 type Appliance struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
 }
 
 type ApplianceAttr struct {
 	Client        *metal.Client
+	listFlags     *listFlags
+	removeFlags   *removeFlags
 	renameFlag    flags.Rename
 	zoneFlag      flags.Zone
 	applianceFlag flags.Appliance
+	valueFlags    attrValueFlags
 }
 
 func (a *Appliance) New(verb Verb) *cobra.Command {
@@ -84,7 +88,7 @@ func (a *Appliance) New(verb Verb) *cobra.Command {
 		a.renameFlag.Add(cmd.Flags(), appliance)
 	}
 
-	attr := ApplianceAttr{Client: a.Client}
+	attr := ApplianceAttr{Client: a.Client, listFlags: a.listFlags, removeFlags: a.removeFlags}
 	cmd.AddCommand(attr.New(verb))
 
 	return &cmd
@@ -103,8 +107,10 @@ func (a *Appliance) create(appliance string) error {
 
 func (a *Appliance) list(glob string) error {
 	type row struct{ Zone, Appliance string }
-	t := table.New()
-	defer t.Flush()
+	t, err := a.listFlags.newWriter(a.Client, appliance)
+	if err != nil {
+		return err
+	}
 
 	r := a.Client.NewApplianceReader(a.zoneFlag.Val(), glob)
 
@@ -116,10 +122,10 @@ func (a *Appliance) list(glob string) error {
 		_ = t.Write(row{
 			Zone:      resp.GetZone(),
 			Appliance: resp.GetName(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *Appliance) update(appliance string) error {
@@ -137,14 +143,18 @@ func (a *Appliance) update(appliance string) error {
 }
 
 func (a *Appliance) remove(glob string) error {
-	req := pb.DeleteAppliancesRequest_builder{
-		Zone: a.zoneFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := a.Client.NewApplianceReader(a.zoneFlag.Val(), glob).Responses()
 
-	_, err := a.Client.Metal.DeleteAppliances(a.Client.Context(), req)
+	return removeMatches(a.removeFlags, appliance, glob, matches, nil, func(name string) error {
+		req := pb.DeleteAppliancesRequest_builder{
+			Zone: a.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := a.Client.Metal.DeleteAppliances(a.Client.Context(), req)
+
+		return err
+	})
 }
 
 func (a *ApplianceAttr) New(verb Verb) *cobra.Command {
@@ -206,6 +216,10 @@ func (a *ApplianceAttr) New(verb Verb) *cobra.Command {
 		a.applianceFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		a.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		a.renameFlag.Add(cmd.Flags(), appliance)
 	}
@@ -226,9 +240,14 @@ func (a *ApplianceAttr) create(attr string) error {
 }
 
 func (a *ApplianceAttr) list(glob string) error {
-	type row struct{ Zone, Appliance, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Zone, Appliance, Attr string
+		Value                 attrText
+	}
+	t, err := a.listFlags.newWriter(a.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := a.Client.NewApplianceAttrReader(a.zoneFlag.Val(), a.applianceFlag.Val(), glob)
 
@@ -241,36 +260,46 @@ func (a *ApplianceAttr) list(glob string) error {
 			Zone:      resp.GetZone(),
 			Appliance: resp.GetAppliance(),
 			Attr:      resp.GetName(),
-			Value:     resp.GetValue(),
-		})
+			Value:     attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ApplianceAttr) update(attr string) error {
+	value, err := a.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateApplianceAttrRequest_builder{
 		Zone:      a.zoneFlag.Ptr(),
 		Appliance: a.applianceFlag.Ptr(),
 		Name:      &attr,
 		Fields: pb.UpdateApplianceAttrRequest_Fields_builder{
-			Name: a.renameFlag.Ptr(),
+			Name:  a.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := a.Client.Metal.UpdateApplianceAttr(a.Client.Context(), req)
+	_, err = a.Client.Metal.UpdateApplianceAttr(a.Client.Context(), req)
 
 	return err
 }
 
 func (a *ApplianceAttr) remove(glob string) error {
-	req := pb.DeleteApplianceAttrsRequest_builder{
-		Zone:      a.zoneFlag.Ptr(),
-		Appliance: a.applianceFlag.Ptr(),
-		Glob:      &glob,
-	}.Build()
+	matches := a.Client.NewApplianceAttrReader(a.zoneFlag.Val(), a.applianceFlag.Val(), glob).Responses()
 
-	_, err := a.Client.Metal.DeleteApplianceAttrs(a.Client.Context(), req)
+	return removeMatches(a.removeFlags, appliance+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteApplianceAttrsRequest_builder{
+			Zone:      a.zoneFlag.Ptr(),
+			Appliance: a.applianceFlag.Ptr(),
+			Glob:      &name,
+		}.Build()
 
-	return err
+		_, err := a.Client.Metal.DeleteApplianceAttrs(a.Client.Context(), req)
+
+		return err
+	})
 }
@@ -0,0 +1,600 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"endobit.io/metal-cli/internal/flags"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// kind is an object type in the schema and the RPCs that manage it. Kinds
+// are listed in dependency order, parents and referenced objects first.
+type kind struct {
+	path    string   // in the schema, like "zones.racks"
+	as      string   // the path its objects are known by, if not path
+	scope   []string // request fields naming the parents, like "zone"
+	inherit []string // parent names sent as fields, like a host's cluster
+	create  request
+	update  request
+	remove  request
+}
+
+// request sends a metal RPC with its request given as JSON fields.
+type request func(ctx context.Context, fields map[string]any) error
+
+// rpc adapts a metal client method to a request.
+func rpc[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp any](method func(context.Context, PReq, ...grpc.CallOption) (Resp, error)) request {
+	return func(ctx context.Context, fields map[string]any) error {
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+
+		req := PReq(new(Req))
+
+		if err := protojson.Unmarshal(b, req); err != nil {
+			return err
+		}
+
+		if err := clearFields(req, fields); err != nil {
+			return err
+		}
+
+		_, err = method(ctx, req)
+
+		return err
+	}
+}
+
+// cleared is the value of an update field to clear. The server leaves the
+// fields missing from an update unchanged and sets the ones present, so a
+// field is cleared by sending it set to its zero value, as host set --rack ""
+// does. Cleared is null in JSON, which protojson reads as leaving the field
+// out, so the field is set on the request afterwards, see clearFields.
+type cleared struct{}
+
+func (cleared) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// clearFields sets the update fields marked cleared to their zero value,
+// present on the request so the server clears them rather than leaving them
+// unchanged.
+func clearFields(req proto.Message, fields map[string]any) error {
+	changes, _ := fields["fields"].(map[string]any)
+
+	var m protoreflect.Message
+
+	for _, name := range slices.Sorted(maps.Keys(changes)) {
+		if _, ok := changes[name].(cleared); !ok {
+			continue
+		}
+
+		if m == nil {
+			r := req.ProtoReflect()
+
+			fd := r.Descriptor().Fields().ByName("fields")
+			if fd == nil || fd.Message() == nil {
+				return fmt.Errorf("%s has no fields to clear", r.Descriptor().Name())
+			}
+
+			m = r.Mutable(fd).Message()
+		}
+
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("cannot clear %s, %s has no such field", name, m.Descriptor().Name())
+		}
+
+		m.Set(fd, m.NewField(fd))
+	}
+
+	return nil
+}
+
+func (r *Root) kinds() []kind {
+	m := r.Client.Metal
+
+	return []kind{
+		{
+			path:   "attrs",
+			create: rpc(m.CreateGlobalAttr),
+			update: rpc(m.UpdateGlobalAttr),
+			remove: rpc(m.DeleteGlobalAttrs),
+		},
+		{
+			path:   "makes",
+			create: rpc(m.CreateMake),
+			update: rpc(m.UpdateMake),
+			remove: rpc(m.DeleteMakes),
+		},
+		{
+			path:   "makes.models",
+			scope:  []string{"make"},
+			create: rpc(m.CreateModel),
+			update: rpc(m.UpdateModel),
+			remove: rpc(m.DeleteModels),
+		},
+		{
+			path:   "makes.models.attrs",
+			scope:  []string{"model"},
+			create: rpc(m.CreateModelAttr),
+			update: rpc(m.UpdateModelAttr),
+			remove: rpc(m.DeleteModelAttrs),
+		},
+		{
+			path:   "zones",
+			create: rpc(m.CreateZone),
+			update: rpc(m.UpdateZone),
+			remove: rpc(m.DeleteZones),
+		},
+		{
+			path:   "zones.attrs",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateZoneAttr),
+			update: rpc(m.UpdateZoneAttr),
+			remove: rpc(m.DeleteZoneAttrs),
+		},
+		{
+			path:   "zones.networks",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateNetwork),
+			update: rpc(m.UpdateNetwork),
+			remove: rpc(m.DeleteNetworks),
+		},
+		{
+			path:   "zones.racks",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateRack),
+			update: rpc(m.UpdateRack),
+			remove: rpc(m.DeleteRacks),
+		},
+		{
+			path:   "zones.racks.attrs",
+			scope:  []string{"zone", "rack"},
+			create: rpc(m.CreateRackAttr),
+			update: rpc(m.UpdateRackAttr),
+			remove: rpc(m.DeleteRackAttrs),
+		},
+		{
+			path:   "zones.appliances",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateAppliance),
+			update: rpc(m.UpdateAppliance),
+			remove: rpc(m.DeleteAppliances),
+		},
+		{
+			path:   "zones.appliances.attrs",
+			scope:  []string{"zone", "appliance"},
+			create: rpc(m.CreateApplianceAttr),
+			update: rpc(m.UpdateApplianceAttr),
+			remove: rpc(m.DeleteApplianceAttrs),
+		},
+		{
+			path:   "zones.environments",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateEnvironment),
+			update: rpc(m.UpdateEnvironment),
+			remove: rpc(m.DeleteEnvironments),
+		},
+		{
+			path:   "zones.environments.attrs",
+			scope:  []string{"zone", "environment"},
+			create: rpc(m.CreateEnvironmentAttr),
+			update: rpc(m.UpdateEnvironmentAttr),
+			remove: rpc(m.DeleteEnvironmentAttrs),
+		},
+		{
+			path:   "zones.clusters",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateCluster),
+			update: rpc(m.UpdateCluster),
+			remove: rpc(m.DeleteClusters),
+		},
+		{
+			path:   "zones.clusters.attrs",
+			scope:  []string{"zone", "cluster"},
+			create: rpc(m.CreateClusterAttr),
+			update: rpc(m.UpdateClusterAttr),
+			remove: rpc(m.DeleteClusterAttrs),
+		},
+		{
+			path:   "zones.hosts",
+			scope:  []string{"zone"},
+			create: rpc(m.CreateHost),
+			update: rpc(m.UpdateHost),
+			remove: rpc(m.DeleteHosts),
+		},
+		{
+			path:    "zones.clusters.hosts",
+			as:      "zones.hosts",
+			scope:   []string{"zone"},
+			inherit: []string{"cluster"},
+			create:  rpc(m.CreateHost),
+			update:  rpc(m.UpdateHost),
+			remove:  rpc(m.DeleteHosts),
+		},
+		{
+			path:   "zones.hosts.interfaces",
+			scope:  []string{"zone", "host"},
+			create: rpc(m.CreateHostInterface),
+			update: rpc(m.UpdateHostInterface),
+			remove: rpc(m.DeleteHostInterfaces),
+		},
+		{
+			path:   "zones.clusters.hosts.interfaces",
+			as:     "zones.hosts.interfaces",
+			scope:  []string{"zone", "host"},
+			create: rpc(m.CreateHostInterface),
+			update: rpc(m.UpdateHostInterface),
+			remove: rpc(m.DeleteHostInterfaces),
+		},
+	}
+}
+
+// object is one named object of a kind found in a schema.
+type object struct {
+	kind    *kind
+	id      string            // path with names, like zones[east].racks[r1]
+	parents map[string]string // names of the parents by singular, like "zone"
+	name    string
+	fields  map[string]any
+}
+
+// action is one RPC of an apply plan.
+type action struct {
+	verb   string // create, update or delete
+	object *object
+	fields map[string]any // changed fields for update
+}
+
+// objects returns the objects of every kind in a schema tree by id. It fails
+// on parts of the schema that apply does not know how to reconcile, rather
+// than silently leaving them alone.
+//
+// Hosts are known by zone and name wherever they are listed, so moving one
+// between clusters is an update of its cluster, and listing one twice is an
+// error.
+func objects(kinds []kind, tree any) (map[string]*object, error) {
+	byPath := make(map[string]*kind, len(kinds))
+	for i := range kinds {
+		byPath[kinds[i].path] = &kinds[i]
+	}
+
+	out := make(map[string]*object)
+
+	var walk func(path, id string, parents map[string]string, m map[string]any) error
+
+	walk = func(path, id string, parents map[string]string, m map[string]any) error {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+
+			kd, ok := byPath[p]
+			if !ok {
+				if path == "" {
+					return fmt.Errorf("apply does not support %q in the schema", k)
+				}
+
+				continue // a field
+			}
+
+			list, ok := m[k].([]any)
+			if !ok {
+				return fmt.Errorf("%s: expected a list", childID(id, k))
+			}
+
+			for _, e := range list {
+				obj, ok := e.(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s: expected objects", childID(id, k))
+				}
+
+				name, _ := obj["name"].(string)
+				if name == "" {
+					return fmt.Errorf("%s: object without a name", childID(id, k))
+				}
+
+				as := kd.path
+				if kd.as != "" {
+					as = kd.as
+				}
+
+				o := object{
+					kind:    kd,
+					id:      objectID(as, parents, name),
+					parents: parents,
+					name:    name,
+					fields:  make(map[string]any),
+				}
+
+				for f, v := range obj {
+					if _, child := byPath[p+"."+f]; !child && f != "name" {
+						o.fields[f] = v
+					}
+				}
+
+				for _, f := range kd.inherit {
+					o.fields[f] = parents[f]
+				}
+
+				if _, dup := out[o.id]; dup {
+					return fmt.Errorf("%s[%s]: duplicate object, %s is listed twice", childID(id, k), name, o.id)
+				}
+
+				out[o.id] = &o
+
+				sub := maps.Clone(parents)
+				if sub == nil {
+					sub = make(map[string]string)
+				}
+
+				sub[strings.TrimSuffix(k, "s")] = name
+
+				if err := walk(p, o.id, sub, obj); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	m, ok := tree.(map[string]any)
+	if !ok {
+		return out, nil
+	}
+
+	return out, walk("", "", nil, m)
+}
+
+// objectID is the id of an object of the kind at path, with the names of its
+// parents, like zones[east].hosts[h1].
+func objectID(path string, parents map[string]string, name string) string {
+	segs := strings.Split(path, ".")
+
+	for i, s := range segs[:len(segs)-1] {
+		segs[i] = s + "[" + parents[strings.TrimSuffix(s, "s")] + "]"
+	}
+
+	segs[len(segs)-1] += "[" + name + "]"
+
+	return strings.Join(segs, ".")
+}
+
+func childID(id, key string) string {
+	if id == "" {
+		return key
+	}
+
+	return id + "." + key
+}
+
+// plan returns the RPCs that turn from into to: creates and updates with
+// only the changed fields in dependency order, then with prune the deletes
+// in the reverse order. With prune the updates also clear the fields missing
+// from to.
+func plan(kinds []kind, from, to any, prune bool) ([]action, error) {
+	have, err := objects(kinds, from)
+	if err != nil {
+		return nil, err
+	}
+
+	want, err := objects(kinds, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []action
+
+	for i := range kinds {
+		for _, id := range slices.Sorted(maps.Keys(want)) {
+			o := want[id]
+			if o.kind != &kinds[i] {
+				continue
+			}
+
+			cur, ok := have[id]
+			if !ok {
+				actions = append(actions, action{verb: "create", object: o, fields: o.fields})
+
+				continue
+			}
+
+			changed := make(map[string]any)
+
+			for f, v := range o.fields {
+				if !reflect.DeepEqual(cur.fields[f], v) {
+					changed[f] = v
+				}
+			}
+
+			// A host moved out of its cluster leaves it, and with prune
+			// any other field missing from to is cleared.
+			for f := range cur.fields {
+				if _, ok := o.fields[f]; !ok && (prune || slices.Contains(cur.kind.inherit, f)) {
+					changed[f] = cleared{}
+				}
+			}
+
+			if len(changed) > 0 {
+				actions = append(actions, action{verb: "update", object: o, fields: changed})
+			}
+		}
+	}
+
+	if !prune {
+		return actions, nil
+	}
+
+	for i := len(kinds) - 1; i >= 0; i-- {
+		for _, id := range slices.Sorted(maps.Keys(have)) {
+			o := have[id]
+			if o.kind == &kinds[i] {
+				if _, ok := want[id]; !ok {
+					actions = append(actions, action{verb: "delete", object: o})
+				}
+			}
+		}
+	}
+
+	return actions, nil
+}
+
+// run sends the action's RPCs. A create is followed by an update with the
+// object's fields, the same as add does.
+func (a *action) run(ctx context.Context) error {
+	o := a.object
+
+	req := make(map[string]any)
+	for _, s := range o.kind.scope {
+		req[s] = o.parents[s]
+	}
+
+	fields := a.fields
+
+	switch a.verb {
+	case "create":
+		req["name"] = o.name
+		if err := o.kind.create(ctx, req); err != nil {
+			return err
+		}
+
+		if len(fields) == 0 {
+			return nil
+		}
+
+		req["fields"] = fields
+
+		return o.kind.update(ctx, req)
+
+	case "update":
+		req["name"] = o.name
+		req["fields"] = fields
+
+		return o.kind.update(ctx, req)
+
+	case "delete":
+		glob, err := globQuote(o.name)
+		if err != nil {
+			return err
+		}
+
+		req["glob"] = glob
+
+		return o.kind.remove(ctx, req)
+	}
+
+	return nil
+}
+
+func (a *action) String() string {
+	s := a.verb + " " + a.object.id
+
+	for _, f := range slices.Sorted(maps.Keys(a.fields)) {
+		b, _ := json.Marshal(a.fields[f])
+		s += " " + f + "=" + string(b)
+	}
+
+	return s
+}
+
+type applyFlags struct {
+	file   flags.File
+	format flags.Format
+	prune  flags.Prune
+	dryRun flags.DryRun
+	yes    flags.Yes
+}
+
+func (r *Root) apply(f *applyFlags) error {
+	doc, err := readSchema(f.file.Val(), f.format.Val())
+	if err != nil {
+		return err
+	}
+
+	from, err := r.serverTree()
+	if err != nil {
+		return err
+	}
+
+	to, err := schemaTree(doc)
+	if err != nil {
+		return err
+	}
+
+	actions, err := plan(r.kinds(), from, to, f.prune.Val())
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("No changes.")
+
+		return nil
+	}
+
+	var deletes int
+
+	for i := range actions {
+		fmt.Println(actions[i].String())
+
+		if actions[i].verb == "delete" {
+			deletes++
+		}
+	}
+
+	if f.dryRun.Val() {
+		return nil
+	}
+
+	if deletes > 0 && !f.yes.Val() {
+		ok, err := confirm(fmt.Sprintf("Apply these changes, deleting %d objects?", deletes))
+		if err != nil {
+			return fmt.Errorf("%w, use --yes to delete without confirming", err)
+		}
+
+		if !ok {
+			return errors.New("apply cancelled")
+		}
+	}
+
+	if err := r.backupBefore("apply"); err != nil {
+		return err
+	}
+
+	for i := range actions {
+		if err := actions[i].run(r.Client.Context()); err != nil {
+			return fmt.Errorf("%s: %w", actions[i].object.id, err)
+		}
+	}
+
+	return nil
+}
+
+// serverTree returns the schema on the server as a tree, see schemaTree.
+func (r *Root) serverTree() (any, error) {
+	resp, err := r.Client.Metal.ReadSchema(r.Client.Context(), &pb.ReadSchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaTree(resp.GetSchema())
+}
@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestPlan(t *testing.T) {
+	kinds := []kind{
+		{path: "zones"},
+		{path: "zones.racks", scope: []string{"zone"}},
+	}
+
+	from := map[string]any{
+		"zones": []any{
+			map[string]any{
+				"name":      "east",
+				"time_zone": "UTC",
+				"racks":     []any{map[string]any{"name": "r1"}},
+			},
+		},
+	}
+
+	to := map[string]any{
+		"zones": []any{
+			map[string]any{"name": "east"},
+			map[string]any{"name": "west", "time_zone": "PST8PDT"},
+		},
+	}
+
+	tests := []struct {
+		prune bool
+		want  []string
+	}{
+		{
+			prune: false,
+			want: []string{
+				`create zones[west] time_zone="PST8PDT"`,
+			},
+		},
+		{
+			prune: true,
+			want: []string{
+				`update zones[east] time_zone=null`,
+				`create zones[west] time_zone="PST8PDT"`,
+				`delete zones[east].racks[r1]`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		actions, err := plan(kinds, from, to, tt.prune)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for i := range actions {
+			got = append(got, actions[i].String())
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("prune %v: got %q, want %q", tt.prune, got, tt.want)
+		}
+	}
+}
+
+func TestPlanHosts(t *testing.T) {
+	kinds := []kind{
+		{path: "zones"},
+		{path: "zones.clusters", scope: []string{"zone"}},
+		{path: "zones.hosts", scope: []string{"zone"}},
+		{path: "zones.clusters.hosts", as: "zones.hosts", scope: []string{"zone"}, inherit: []string{"cluster"}},
+		{path: "zones.hosts.interfaces", scope: []string{"zone", "host"}},
+		{path: "zones.clusters.hosts.interfaces", as: "zones.hosts.interfaces", scope: []string{"zone", "host"}},
+	}
+
+	h1 := map[string]any{
+		"name":       "h1",
+		"rack":       "r1",
+		"interfaces": []any{map[string]any{"name": "eth0"}},
+	}
+
+	withZone := func(hosts []any, clusters ...map[string]any) map[string]any {
+		z := map[string]any{"name": "east", "hosts": hosts}
+
+		var l []any
+		for _, c := range clusters {
+			l = append(l, c)
+		}
+
+		z["clusters"] = l
+
+		return map[string]any{"zones": []any{z}}
+	}
+
+	withHosts := func(name string, hosts ...any) map[string]any {
+		return map[string]any{"name": name, "hosts": hosts}
+	}
+
+	unclustered := withZone([]any{h1}, withHosts("c1"), withHosts("c2"))
+	inC1 := withZone(nil, withHosts("c1", h1), withHosts("c2"))
+	inC2 := withZone(nil, withHosts("c1"), withHosts("c2", h1))
+
+	tests := []struct {
+		name     string
+		from, to any
+		want     []string
+	}{
+		{"between clusters", inC1, inC2, []string{`update zones[east].hosts[h1] cluster="c2"`}},
+		{"into a cluster", unclustered, inC1, []string{`update zones[east].hosts[h1] cluster="c1"`}},
+		{"out of a cluster", inC2, unclustered, []string{`update zones[east].hosts[h1] cluster=null`}},
+		{"same cluster", inC1, inC1, nil},
+	}
+
+	for _, tt := range tests {
+		for _, prune := range []bool{false, true} {
+			actions, err := plan(kinds, tt.from, tt.to, prune)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for i := range actions {
+				got = append(got, actions[i].String())
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s, prune %v: got %q, want %q", tt.name, prune, got, tt.want)
+			}
+		}
+	}
+
+	twice := withZone([]any{h1}, withHosts("c1", h1))
+
+	if _, err := plan(kinds, twice, twice, false); err == nil {
+		t.Error("planned a schema that lists a host twice")
+	}
+}
+
+func TestClearFields(t *testing.T) {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+
+		return f
+	}
+
+	// An update request with fields that have presence, like the metal ones.
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("UpdateZoneRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("fields", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Fields"),
+				},
+			},
+			{
+				Name: proto.String("Fields"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("time_zone", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := dynamicpb.NewMessage(fd.Messages().ByName("UpdateZoneRequest"))
+
+	if err := clearFields(req, map[string]any{
+		"name":   "east",
+		"fields": map[string]any{"name": "west", "time_zone": cleared{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := req.Get(fd.Messages().ByName("UpdateZoneRequest").Fields().ByName("fields")).Message()
+	desc := fd.Messages().ByName("Fields").Fields()
+
+	if !fields.Has(desc.ByName("time_zone")) {
+		t.Error("time_zone is not set")
+	}
+
+	if fields.Has(desc.ByName("name")) {
+		t.Error("name is set, only cleared fields should be")
+	}
+
+	// The server sees the cleared field, unlike one left out.
+	b, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := dynamicpb.NewMessage(fd.Messages().ByName("UpdateZoneRequest"))
+
+	if err := proto.Unmarshal(b, sent); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sent.Get(fd.Messages().ByName("UpdateZoneRequest").Fields().ByName("fields")).Message()
+	if !got.Has(desc.ByName("time_zone")) {
+		t.Error("time_zone is not sent")
+	}
+
+	if err := clearFields(req, map[string]any{
+		"fields": map[string]any{"rack": cleared{}},
+	}); err == nil {
+		t.Error("cleared a field the request does not have")
+	}
+}
@@ -0,0 +1,302 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal"
+
+	"endobit.io/metal-cli/internal/flags"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+type GlobalAttr struct {
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	valueFlags  attrValueFlags
+}
+
+// attrValueFlags are the flags every attr command uses to set a value.
+// Values are stored as strings, typed values are checked and normalized
+// before anything is sent so the server only ever sees well formed data.
+type attrValueFlags struct {
+	valueFlag     flags.Value
+	valueFileFlag flags.ValueFile
+	valueTypeFlag flags.ValueType
+	value         *string
+}
+
+func (a *GlobalAttr) New(verb Verb) *cobra.Command {
+	var cmd cobra.Command
+
+	switch verb {
+	case Add:
+		cmd = cobra.Command{
+			Use:   attribute + " name",
+			Short: "Add a global " + attribute,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if err := a.create(args[0]); err != nil {
+					return err
+				}
+
+				return a.update(args[0])
+			},
+		}
+	case Set:
+		cmd = cobra.Command{
+			Use:   attribute + " name",
+			Short: "Set a global " + attribute + "'s properties",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return a.update(args[0])
+			},
+		}
+	case List:
+		cmd = cobra.Command{
+			Use:   attribute + " [glob]",
+			Short: "List one or more global " + attribute + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return a.list(glob)
+			},
+		}
+	case Remove:
+		cmd = cobra.Command{
+			Use:   attribute + " glob",
+			Short: "Remove one or more global " + attribute + "s",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return a.remove(args[0])
+			},
+		}
+	}
+
+	if verb == Add || verb == Set {
+		a.valueFlags.Add(&cmd, attribute)
+	}
+
+	if verb == Set {
+		a.renameFlag.Add(cmd.Flags(), attribute)
+	}
+
+	return &cmd
+}
+
+func (a *GlobalAttr) create(attr string) error {
+	var req pb.CreateGlobalAttrRequest
+
+	req.SetName(attr)
+	_, err := a.Client.Metal.CreateGlobalAttr(a.Client.Context(), &req)
+
+	return err
+}
+
+func (a *GlobalAttr) list(glob string) error {
+	type row struct {
+		Attr  string
+		Value attrText
+	}
+	t, err := a.listFlags.newWriter(a.Client, attribute)
+	if err != nil {
+		return err
+	}
+
+	r := a.Client.NewGlobalAttrReader(glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Attr:  resp.GetName(),
+			Value: attrText(resp.GetValue()),
+		}, resp)
+	}
+
+	return t.Flush()
+}
+
+func (a *GlobalAttr) update(attr string) error {
+	value, err := a.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
+	req := pb.UpdateGlobalAttrRequest_builder{
+		Name: &attr,
+		Fields: pb.UpdateGlobalAttrRequest_Fields_builder{
+			Name:  a.renameFlag.Ptr(),
+			Value: value,
+		}.Build(),
+	}.Build()
+
+	_, err = a.Client.Metal.UpdateGlobalAttr(a.Client.Context(), req)
+
+	return err
+}
+
+func (a *GlobalAttr) remove(glob string) error {
+	matches := a.Client.NewGlobalAttrReader(glob).Responses()
+	every := a.Client.NewGlobalAttrReader("").Responses()
+
+	return removeMatches(a.removeFlags, "global "+attribute, glob, matches, every, func(name string) error {
+		var req pb.DeleteGlobalAttrsRequest
+
+		req.SetGlob(name)
+		_, err := a.Client.Metal.DeleteGlobalAttrs(a.Client.Context(), &req)
+
+		return err
+	})
+}
+
+func (f *attrValueFlags) Add(cmd *cobra.Command, object string) {
+	f.valueFlag.Add(cmd.Flags(), object)
+	f.valueFileFlag.Add(cmd.Flags(), object)
+	f.valueTypeFlag.Add(cmd.Flags(), object)
+
+	cmd.MarkFlagsMutuallyExclusive("value", "value-file")
+
+	// Resolve before RunE so a bad value fails an add before the attr
+	// is created.
+	cmd.PreRunE = func(_ *cobra.Command, _ []string) error {
+		return f.resolve()
+	}
+}
+
+// Ptr returns the validated value to send, or nil if no value was given so
+// the update leaves the current value alone.
+func (f *attrValueFlags) Ptr() (*string, error) {
+	if f.value == nil {
+		if err := f.resolve(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.value, nil
+}
+
+func (f *attrValueFlags) resolve() error {
+	var raw string
+
+	switch {
+	case f.valueFlag.Changed():
+		raw = f.valueFlag.Val()
+	case f.valueFileFlag.Changed():
+		b, err := readValueFile(f.valueFileFlag.Val())
+		if err != nil {
+			return err
+		}
+
+		raw = string(b)
+	case f.valueTypeFlag.Changed():
+		return errors.New("--type needs --value or --value-file")
+	default:
+		return nil
+	}
+
+	value, err := attrValue(f.valueTypeFlag.Val(), raw)
+	if err != nil {
+		return err
+	}
+
+	f.value = &value
+
+	return nil
+}
+
+func readValueFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(name)
+}
+
+// attrValue checks raw against the value type and returns its normalized
+// form. Lists are stored as JSON arrays of strings.
+func attrValue(typ, raw string) (string, error) {
+	switch typ {
+	case "", "string":
+		return raw, nil
+
+	case "bool":
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return "", fmt.Errorf("invalid bool value %q", raw)
+		}
+
+		return strconv.FormatBool(b), nil
+
+	case "int":
+		i, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid int value %q", raw)
+		}
+
+		return strconv.FormatInt(i, 10), nil
+
+	case "list":
+		var list []string
+
+		if s := strings.TrimSpace(raw); strings.HasPrefix(s, "[") {
+			if err := json.Unmarshal([]byte(s), &list); err != nil {
+				return "", fmt.Errorf("invalid list value: %w", err)
+			}
+		} else if s != "" {
+			for _, item := range strings.Split(s, ",") {
+				list = append(list, strings.TrimSpace(item))
+			}
+		}
+
+		if list == nil {
+			list = []string{}
+		}
+
+		b, err := json.Marshal(list)
+
+		return string(b), err
+
+	case "json":
+		var buf bytes.Buffer
+
+		if err := json.Compact(&buf, []byte(raw)); err != nil {
+			return "", fmt.Errorf("invalid json value: %w", err)
+		}
+
+		return buf.String(), nil
+	}
+
+	return "", fmt.Errorf("unknown value type %q", typ)
+}
+
+// attrText is a stored value in list output. Values are stored without
+// their type, so as a table cell a value is shown as stored but cut to its
+// first line, other formats get the whole value.
+type attrText string
+
+func (a attrText) String() string {
+	value := string(a)
+
+	if first, _, ok := strings.Cut(value, "\n"); ok {
+		return first + " ..."
+	}
+
+	return value
+}
@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/table"
+
+	"endobit.io/metal-cli/internal/backup"
+	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/protoyaml"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// backupBefore backs up the schema before command changes the server, unless
+// backups are off for the context. It is called after any confirming, right
+// before the first change, so a dry run or cancelled command saves nothing.
+func (r *Root) backupBefore(command string) error {
+	if r.Backups == nil {
+		return nil
+	}
+
+	store, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	if !store.Auto() {
+		return nil
+	}
+
+	if _, err := r.snapshot(store, command); err != nil {
+		return fmt.Errorf("backup before %s: %w", command, err)
+	}
+
+	return nil
+}
+
+// backupFirst backs up the schema before each command below cmd runs, for
+// commands like add and set that change the server without confirming. The
+// backup is taken in RunE, so commands check their flags in PreRunE, and one
+// that fails validation reads and saves nothing.
+func (r *Root) backupFirst(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		r.backupFirst(c)
+	}
+
+	run := cmd.RunE
+	if run == nil {
+		return
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		command := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+
+		if err := r.backupBefore(command); err != nil {
+			return err
+		}
+
+		return run(cmd, args)
+	}
+}
+
+// snapshot saves the schema on the server as the newest backup.
+func (r *Root) snapshot(store *backup.Store, command string) (*backup.Snapshot, error) {
+	resp, err := r.Client.Metal.ReadSchema(r.Client.Context(), &pb.ReadSchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := protoyaml.Marshal(resp.GetSchema(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Save(b, command)
+}
+
+func (r *Root) backup() error {
+	store, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	snap, err := r.snapshot(store, "backup")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Backup %d saved to %s.\n", snap.ID, snap.Path)
+
+	return nil
+}
+
+func (r *Root) listBackups() error {
+	type row struct{ Backup, Time, Command string }
+
+	store, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	snaps, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	t := table.New()
+	defer t.Flush()
+
+	for _, snap := range snaps {
+		_ = t.Write(row{
+			Backup:  strconv.Itoa(snap.ID),
+			Time:    snap.Time.Local().Format(time.DateTime),
+			Command: snap.Command,
+		})
+	}
+
+	return nil
+}
+
+func (r *Root) showBackup(id string) error {
+	snap, err := r.getBackup(id)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(snap.Path)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+
+	return err
+}
+
+// getBackup returns a backup by its number, or the newest for "".
+func (r *Root) getBackup(id string) (*backup.Snapshot, error) {
+	var n int
+
+	if id != "" {
+		var err error
+
+		if n, err = strconv.Atoi(id); err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid backup %q, want a number from backup list", id)
+		}
+	}
+
+	store, err := r.Backups()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Get(n)
+}
+
+// confirmFlags are the flags of commands that show changes and make them
+// after confirming.
+type confirmFlags struct {
+	yes    flags.Yes
+	dryRun flags.DryRun
+}
+
+func (r *Root) restore(id string, f *confirmFlags) error {
+	snap, err := r.getBackup(id)
+	if err != nil {
+		return err
+	}
+
+	from, err := r.serverTree()
+	if err != nil {
+		return err
+	}
+
+	to, err := backupTree(snap)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring backup %d from before %s.\n", snap.ID, snap.Command)
+
+	return r.reconcile(from, to, "restore", f)
+}
+
+// undo restores the newest backup that differs from the server. Undo itself
+// is backed up first, so that is the one before the last change.
+func (r *Root) undo(f *confirmFlags) error {
+	store, err := r.Backups()
+	if err != nil {
+		return err
+	}
+
+	snaps, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	from, err := r.serverTree()
+	if err != nil {
+		return err
+	}
+
+	for i := range snaps {
+		to, err := backupTree(&snaps[i])
+		if err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+
+		fmt.Printf("Undoing %s with backup %d.\n", snaps[i].Command, snaps[i].ID)
+
+		return r.reconcile(from, to, "undo", f)
+	}
+
+	return errors.New("nothing to undo, every backup matches the server")
+}
+
+func backupTree(snap *backup.Snapshot) (any, error) {
+	doc, err := readSchema([]string{snap.Path}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return schemaTree(doc)
+}
+
+// reconcile shows the calls that make the server match a schema, with
+// prune, and makes them after confirming or straight away with --yes. With
+// --dry-run they are only shown.
+func (r *Root) reconcile(from, to any, what string, f *confirmFlags) error {
+	actions, err := plan(r.kinds(), from, to, true)
+	if err != nil {
+		return err
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("No changes.")
+
+		return nil
+	}
+
+	for i := range actions {
+		fmt.Println(actions[i].String())
+	}
+
+	if f.dryRun.Val() {
+		return nil
+	}
+
+	if !f.yes.Val() {
+		ok, err := confirm("Make these changes?")
+		if err != nil {
+			return fmt.Errorf("%w, use --yes to %s without confirming", err, what)
+		}
+
+		if !ok {
+			return errors.New(what + " cancelled")
+		}
+	}
+
+	if err := r.backupBefore(what); err != nil {
+		return err
+	}
+
+	for i := range actions {
+		if err := actions[i].run(r.Client.Context()); err != nil {
+			return fmt.Errorf("%s: %w", actions[i].object.id, err)
+		}
+	}
+
+	return nil
+}
@@ -6,20 +6,24 @@ import (
 	"endobit.io/metal"
 	"endobit.io/metal-cli/internal/flags"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
-	"endobit.io/table"
 )
 
 type Cluster struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
 }
 
 type ClusterAttr struct {
 	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
 	renameFlag  flags.Rename
 	zoneFlag    flags.Zone
 	clusterFlag flags.Cluster
+	valueFlags  attrValueFlags
 }
 
 func (c *Cluster) New(verb Verb) *cobra.Command {
@@ -73,31 +77,39 @@ func (c *Cluster) New(verb Verb) *cobra.Command {
 		}
 	}
 
+	c.zoneFlag.Add(cmd.Flags(), cluster)
+
 	if verb == Add || verb == Set || verb == Remove {
-		c.zoneFlag.Add(cmd.PersistentFlags(), cluster)
-		c.zoneFlag.Required(cmd.PersistentFlags())
+		c.zoneFlag.Required(cmd.Flags())
 	}
 
 	if verb == Set {
 		c.renameFlag.Add(cmd.Flags(), cluster)
 	}
 
+	attr := ClusterAttr{Client: c.Client, listFlags: c.listFlags, removeFlags: c.removeFlags}
+	cmd.AddCommand(attr.New(verb))
+
 	return &cmd
 }
 
 func (c *Cluster) create(cluster string) error {
-	var req pb.CreateClusterRequest
+	req := pb.CreateClusterRequest_builder{
+		Zone: c.zoneFlag.Ptr(),
+		Name: &cluster,
+	}.Build()
 
-	req.SetName(cluster)
-	_, err := c.Client.Metal.CreateCluster(c.Client.Context(), &req)
+	_, err := c.Client.Metal.CreateCluster(c.Client.Context(), req)
 
 	return err
 }
 
 func (c *Cluster) list(glob string) error {
 	type row struct{ Zone, Cluster string }
-	t := table.New()
-	defer t.Flush()
+	t, err := c.listFlags.newWriter(c.Client, cluster)
+	if err != nil {
+		return err
+	}
 
 	r := c.Client.NewClusterReader(c.zoneFlag.Val(), glob)
 
@@ -109,10 +121,10 @@ func (c *Cluster) list(glob string) error {
 		_ = t.Write(row{
 			Zone:    resp.GetZone(),
 			Cluster: resp.GetName(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (c *Cluster) update(cluster string) error {
@@ -130,14 +142,18 @@ func (c *Cluster) update(cluster string) error {
 }
 
 func (c *Cluster) remove(glob string) error {
-	req := pb.DeleteClustersRequest_builder{
-		Zone: c.zoneFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := c.Client.NewClusterReader(c.zoneFlag.Val(), glob).Responses()
 
-	_, err := c.Client.Metal.DeleteClusters(c.Client.Context(), req)
+	return removeMatches(c.removeFlags, cluster, glob, matches, nil, func(name string) error {
+		req := pb.DeleteClustersRequest_builder{
+			Zone: c.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := c.Client.Metal.DeleteClusters(c.Client.Context(), req)
+
+		return err
+	})
 }
 
 func (c *ClusterAttr) New(verb Verb) *cobra.Command {
@@ -199,6 +215,10 @@ func (c *ClusterAttr) New(verb Verb) *cobra.Command {
 		c.clusterFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		c.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		c.renameFlag.Add(cmd.Flags(), cluster)
 	}
@@ -219,9 +239,14 @@ func (c *ClusterAttr) create(attr string) error {
 }
 
 func (c *ClusterAttr) list(glob string) error {
-	type row struct{ Zone, Cluster, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Zone, Cluster, Attr string
+		Value               attrText
+	}
+	t, err := c.listFlags.newWriter(c.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := c.Client.NewClusterAttrReader(c.zoneFlag.Val(), c.clusterFlag.Val(), glob)
 
@@ -234,36 +259,46 @@ func (c *ClusterAttr) list(glob string) error {
 			Zone:    resp.GetZone(),
 			Cluster: resp.GetCluster(),
 			Attr:    resp.GetName(),
-			Value:   resp.GetValue(),
-		})
+			Value:   attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (c *ClusterAttr) update(attr string) error {
+	value, err := c.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateClusterAttrRequest_builder{
 		Zone:    c.zoneFlag.Ptr(),
 		Cluster: c.clusterFlag.Ptr(),
 		Name:    &attr,
 		Fields: pb.UpdateClusterAttrRequest_Fields_builder{
-			Name: c.renameFlag.Ptr(),
+			Name:  c.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := c.Client.Metal.UpdateClusterAttr(c.Client.Context(), req)
+	_, err = c.Client.Metal.UpdateClusterAttr(c.Client.Context(), req)
 
 	return err
 }
 
 func (c *ClusterAttr) remove(glob string) error {
-	req := pb.DeleteClusterAttrsRequest_builder{
-		Zone:    c.zoneFlag.Ptr(),
-		Cluster: c.clusterFlag.Ptr(),
-		Glob:    &glob,
-	}.Build()
+	matches := c.Client.NewClusterAttrReader(c.zoneFlag.Val(), c.clusterFlag.Val(), glob).Responses()
 
-	_, err := c.Client.Metal.DeleteClusterAttrs(c.Client.Context(), req)
+	return removeMatches(c.removeFlags, cluster+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteClusterAttrsRequest_builder{
+			Zone:    c.zoneFlag.Ptr(),
+			Cluster: c.clusterFlag.Ptr(),
+			Glob:    &name,
+		}.Build()
 
-	return err
+		_, err := c.Client.Metal.DeleteClusterAttrs(c.Client.Context(), req)
+
+		return err
+	})
 }
@@ -6,21 +6,40 @@ type Verb int
 
 const (
 	Add Verb = iota
+	Apply
+	Backup
+	Diff
 	Dump
+	Lint
 	List
 	Load
 	Remove
 	Report
 	Set
+	UI
+	Undo
 )
 
+// SkipAuth is the annotation for commands that connect to the server but must
+// not authenticate first, like login.
+const SkipAuth = "skip-auth"
+
+// Offline is the SkipAuth value of commands that do not connect at all, like
+// lint and config, which must run even when the configured context is broken
+// or, for config set, does not exist yet, and backup list and show, which
+// must run when the server is unreachable.
+const Offline = "offline"
+
 const (
 	attribute   = "attr"
 	rack        = "rack"
 	appliance   = "appliance"
 	cluster     = "cluster"
 	host        = "host"
+	iface       = "interface"
 	environment = "environment"
 	model       = "model"
+	network     = "network"
+	vendor      = "make"
 	zone        = "zone"
 )
@@ -0,0 +1,382 @@
+package commands
+
+import (
+	"errors"
+	"io/fs"
+	"iter"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"endobit.io/metal"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// completionTTL is how long names read from the server for shell completion
+// are cached, long enough for the tabs of one command line.
+const completionTTL = 30 * time.Second
+
+// completeFunc completes an arg or flag value, see cobra.Command's
+// ValidArgsFunction.
+type completeFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completion is how to complete the names of one kind of object.
+type completion struct {
+	scope []string // the flags that narrow the names, like zone for racks
+	read  func(c *metal.Client, scope map[string]string) ([]string, error)
+}
+
+// completions are by object, and attrs by their parent object like
+// "rack attr".
+var completions = map[string]completion{
+	zone: {
+		read: func(c *metal.Client, _ map[string]string) ([]string, error) {
+			return objectNames(c.NewZoneReader("").Responses())
+		},
+	},
+	cluster: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewClusterReader(s[zone], "").Responses())
+		},
+	},
+	rack: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewRackReader(s[zone], "").Responses())
+		},
+	},
+	appliance: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewApplianceReader(s[zone], "").Responses())
+		},
+	},
+	environment: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewEnvironmentReader(s[zone], "").Responses())
+		},
+	},
+	network: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewNetworkReader(s[zone], "").Responses())
+		},
+	},
+	host: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewHostReader(s[zone], "").Responses())
+		},
+	},
+	iface: {
+		scope: []string{zone, host},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewHostInterfaceReader(s[zone], s[host], "").Responses())
+		},
+	},
+	vendor: {
+		read: func(c *metal.Client, _ map[string]string) ([]string, error) {
+			return objectNames(c.NewMakeReader("").Responses())
+		},
+	},
+	model: {
+		scope: []string{vendor},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewModelReader(s[vendor], "").Responses())
+		},
+	},
+	attribute: {
+		read: func(c *metal.Client, _ map[string]string) ([]string, error) {
+			return objectNames(c.NewGlobalAttrReader("").Responses())
+		},
+	},
+	zone + " " + attribute: {
+		scope: []string{zone},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewZoneAttrReader(s[zone], "").Responses())
+		},
+	},
+	cluster + " " + attribute: {
+		scope: []string{zone, cluster},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewClusterAttrReader(s[zone], s[cluster], "").Responses())
+		},
+	},
+	rack + " " + attribute: {
+		scope: []string{zone, rack},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewRackAttrReader(s[zone], s[rack], "").Responses())
+		},
+	},
+	appliance + " " + attribute: {
+		scope: []string{zone, appliance},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewApplianceAttrReader(s[zone], s[appliance], "").Responses())
+		},
+	},
+	environment + " " + attribute: {
+		scope: []string{zone, environment},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewEnvironmentAttrReader(s[zone], s[environment], "").Responses())
+		},
+	},
+	model + " " + attribute: {
+		scope: []string{model},
+		read: func(c *metal.Client, s map[string]string) ([]string, error) {
+			return objectNames(c.NewModelAttrReader(s[model], "").Responses())
+		},
+	},
+}
+
+// nameVerbs are the verbs whose object commands take the names of existing
+// objects.
+var nameVerbs = []string{Set.String(), List.String(), Remove.String(), Report.String()}
+
+// AddCompletions completes the object names taken by cmd and the commands
+// below it, and the values of their object flags, from the server.
+func (r *Root) AddCompletions(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		r.AddCompletions(c)
+	}
+
+	for _, fs := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+		fs.VisitAll(func(f *pflag.Flag) {
+			if fn := r.flagCompletion(f.Name); fn != nil {
+				_ = cmd.RegisterFlagCompletionFunc(f.Name, fn)
+			}
+		})
+	}
+
+	if cmd.ValidArgsFunction == nil && cmd.Runnable() {
+		cmd.ValidArgsFunction = r.argCompletion(cmd)
+	}
+}
+
+// flagCompletion returns how to complete a flag, nil for flags that are not
+// named after objects.
+func (r *Root) flagCompletion(name string) completeFunc {
+	if name == "arch" {
+		return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var arches []string
+
+			for arch, n := range pb.Architecture_value {
+				if n != 0 {
+					arches = append(arches, arch)
+				}
+			}
+
+			slices.Sort(arches)
+
+			return matching(arches, toComplete), cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	if _, ok := completions[name]; !ok {
+		return nil
+	}
+
+	return func(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return r.complete(cmd, name, nil, toComplete)
+	}
+}
+
+// argCompletion returns how to complete the args of an object command: the
+// names of existing objects, except for add which makes new ones. Models
+// take their make first.
+func (r *Root) argCompletion(cmd *cobra.Command) completeFunc {
+	verb := cmd
+	for verb.HasParent() && verb.Parent().HasParent() {
+		verb = verb.Parent()
+	}
+
+	if verb == cmd {
+		return nil
+	}
+
+	// Attrs other than global ones are named after their parent, like
+	// "rack attr".
+	object := cmd.Name()
+	if object == attribute && cmd.Parent() != verb {
+		object = cmd.Parent().Name() + " " + attribute
+	}
+
+	if _, ok := completions[object]; !ok {
+		return nil
+	}
+
+	existing := slices.Contains(nameVerbs, verb.Name())
+
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if object == model {
+			switch len(args) {
+			case 0:
+				return r.complete(cmd, vendor, nil, toComplete)
+			case 1:
+				if existing {
+					return r.complete(cmd, model, map[string]string{vendor: args[0]}, toComplete)
+				}
+			}
+
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		if len(args) > 0 || !existing {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		return r.complete(cmd, object, nil, toComplete)
+	}
+}
+
+// complete returns the names of an object starting with toComplete, narrowed
+// by the scope given or else by the command's flags. Errors leave the
+// completion empty rather than break the shell.
+func (r *Root) complete(cmd *cobra.Command, object string, scope map[string]string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// The context's defaults fill in the scope flags, so the session is
+	// resolved before they are read.
+	if r.Resume != nil {
+		if err := r.Resume(cmd); err != nil {
+			cobra.CompDebugln(err.Error(), false)
+
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+	}
+
+	comp := completions[object]
+
+	if scope == nil {
+		scope = make(map[string]string)
+
+		for _, name := range comp.scope {
+			if v, err := cmd.Flags().GetString(name); err == nil && v != "" {
+				scope[name] = v
+			}
+		}
+	}
+
+	all, err := r.cachedNames(object, scope, comp)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), false)
+
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return matching(all, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// cachedNames reads names from the server, or from the cache if they were
+// read in the last completionTTL.
+func (r *Root) cachedNames(object string, scope map[string]string, comp completion) ([]string, error) {
+	var file string
+
+	if r.CompletionDir != nil {
+		dir, err := r.CompletionDir()
+		if err != nil {
+			return nil, err
+		}
+
+		name := url.PathEscape(object)
+		for _, k := range slices.Sorted(maps.Keys(scope)) {
+			name += "," + k + "=" + url.PathEscape(scope[k])
+		}
+
+		file = filepath.Join(dir, name)
+
+		info, err := os.Stat(file)
+
+		switch {
+		case err == nil && time.Since(info.ModTime()) < completionTTL:
+			if b, err := os.ReadFile(file); err == nil {
+				return lines(b), nil
+			}
+		case err != nil && !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	all, err := comp.read(r.Client, scope)
+	if err != nil || file == "" {
+		return all, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return nil, err
+	}
+
+	return all, os.WriteFile(file, []byte(strings.Join(all, "\n")), 0o600)
+}
+
+// forgetNamesAfter drops the names cached for completion after each command
+// below cmd runs, since it may have added, removed or renamed objects.
+func (r *Root) forgetNamesAfter(cmd *cobra.Command) {
+	for _, c := range cmd.Commands() {
+		r.forgetNamesAfter(c)
+	}
+
+	run := cmd.RunE
+	if run == nil {
+		return
+	}
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		defer r.forgetNames()
+
+		return run(cmd, args)
+	}
+}
+
+func (r *Root) forgetNames() {
+	if r.CompletionDir == nil {
+		return
+	}
+
+	if dir, err := r.CompletionDir(); err == nil {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// objectNames returns the sorted names of objects, once each since objects
+// in different zones may share a name.
+func objectNames[T interface{ GetName() string }](seq iter.Seq2[T, error]) ([]string, error) {
+	var s []string
+
+	for resp, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, resp.GetName())
+	}
+
+	slices.Sort(s)
+
+	return slices.Compact(s), nil
+}
+
+func matching(all []string, prefix string) []string {
+	var s []string
+
+	for _, name := range all {
+		if strings.HasPrefix(name, prefix) {
+			s = append(s, name)
+		}
+	}
+
+	return s
+}
+
+func lines(b []byte) []string {
+	return strings.FieldsFunc(string(b), func(r rune) bool {
+		return r == '\n'
+	})
+}
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"errors"
+	"iter"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal"
+)
+
+// fakeCompletions replaces the readers of every completion for the test with
+// one returning a single name, the object and the scope it was read with,
+// like "rack,zone=east".
+func fakeCompletions(t *testing.T) {
+	saved := completions
+
+	t.Cleanup(func() {
+		completions = saved
+	})
+
+	completions = make(map[string]completion)
+
+	for object, c := range saved {
+		completions[object] = completion{
+			scope: c.scope,
+			read: func(_ *metal.Client, scope map[string]string) ([]string, error) {
+				name := object
+				for _, k := range slices.Sorted(maps.Keys(scope)) {
+					name += "," + k + "=" + scope[k]
+				}
+
+				return []string{name}, nil
+			},
+		}
+	}
+}
+
+func TestArgCompletion(t *testing.T) {
+	fakeCompletions(t)
+
+	tests := []struct {
+		verb  Verb
+		path  []string
+		flags []string
+		args  []string
+		want  []string
+	}{
+		{verb: Set, path: []string{model}, want: []string{vendor}},
+		{verb: Set, path: []string{model}, args: []string{"dell"}, want: []string{model + ",make=dell"}},
+		{verb: Set, path: []string{model}, args: []string{"dell", "r640"}},
+		{verb: Add, path: []string{model}, want: []string{vendor}},
+		{verb: Add, path: []string{model}, args: []string{"dell"}},
+		{verb: Add, path: []string{rack}},
+		{verb: Set, path: []string{rack}, flags: []string{"--zone", "east"}, want: []string{rack + ",zone=east"}},
+		{verb: Remove, path: []string{rack}, args: []string{"r1"}},
+		{verb: Set, path: []string{attribute}, want: []string{attribute}},
+		{
+			verb:  Set,
+			path:  []string{rack, attribute},
+			flags: []string{"--zone", "east", "--rack", "r1"},
+			want:  []string{rack + " " + attribute + ",rack=r1,zone=east"},
+		},
+		{verb: Add, path: []string{rack, attribute}, flags: []string{"--rack", "r1"}},
+	}
+
+	for _, tt := range tests {
+		var r Root
+
+		cmd, _, err := r.New(tt.verb).Find(tt.path)
+		if err != nil {
+			t.Fatalf("%s %v: %v", tt.verb, tt.path, err)
+		}
+
+		if err := cmd.ParseFlags(tt.flags); err != nil {
+			t.Fatalf("%s %v: %v", tt.verb, tt.path, err)
+		}
+
+		complete := r.argCompletion(cmd)
+		if complete == nil {
+			t.Fatalf("%s %v: no completion", tt.verb, tt.path)
+		}
+
+		got, directive := complete(cmd, tt.args, "")
+		if !slices.Equal(got, tt.want) || directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("%s %v %q: got %q, %v, want %q", tt.verb, tt.path, tt.args, got, directive, tt.want)
+		}
+	}
+}
+
+// TestCachedNames reads names once into a file named after the object and
+// its scope, and again once the file is older than completionTTL.
+func TestCachedNames(t *testing.T) {
+	dir := t.TempDir()
+
+	r := Root{
+		CompletionDir: func() (string, error) {
+			return dir, nil
+		},
+	}
+
+	reads := 0
+
+	comp := completion{
+		read: func(*metal.Client, map[string]string) ([]string, error) {
+			reads++
+
+			return []string{"a", "b"}, nil
+		},
+	}
+
+	scope := map[string]string{zone: "east west", rack: "r/1"}
+
+	for range 2 {
+		got, err := r.cachedNames(rack+" "+attribute, scope, comp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(got, []string{"a", "b"}) {
+			t.Errorf("got %q, want a and b", got)
+		}
+	}
+
+	if reads != 1 {
+		t.Errorf("read %d times within the TTL, want once", reads)
+	}
+
+	file := filepath.Join(dir, "rack%20attr,rack=r%2F1,zone=east%20west")
+
+	old := time.Now().Add(-2 * completionTTL)
+	if err := os.Chtimes(file, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.cachedNames(rack+" "+attribute, scope, comp); err != nil {
+		t.Fatal(err)
+	}
+
+	if reads != 2 {
+		t.Errorf("read %d times after the TTL, want twice", reads)
+	}
+}
+
+type named string
+
+func (n named) GetName() string {
+	return string(n)
+}
+
+func TestObjectNames(t *testing.T) {
+	seq := func(names []named, err error) iter.Seq2[named, error] {
+		return func(yield func(named, error) bool) {
+			for _, n := range names {
+				if !yield(n, nil) {
+					return
+				}
+			}
+
+			if err != nil {
+				yield("", err)
+			}
+		}
+	}
+
+	got, err := objectNames(seq([]named{"r2", "r1", "r2", "r1", "r3"}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"r1", "r2", "r3"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, err := objectNames(seq([]named{"r1"}, errors.New("unavailable"))); err == nil || got != nil {
+		t.Errorf("got %q, %v, want the error", got, err)
+	}
+}
+
+// TestForgetNamesAfter drops the cache after a command runs, even one that
+// fails.
+func TestForgetNamesAfter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "completion")
+
+	r := Root{
+		CompletionDir: func() (string, error) {
+			return dir, nil
+		},
+	}
+
+	fail := errors.New("failed")
+
+	root := &cobra.Command{Use: "stack"}
+	ok := &cobra.Command{Use: "ok", RunE: func(*cobra.Command, []string) error { return nil }}
+	bad := &cobra.Command{Use: "bad", RunE: func(*cobra.Command, []string) error { return fail }}
+
+	root.AddCommand(ok, bad)
+	r.forgetNamesAfter(root)
+
+	for _, tt := range []struct {
+		cmd *cobra.Command
+		err error
+	}{
+		{ok, nil},
+		{bad, fail},
+	} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tt.cmd.RunE(tt.cmd, nil); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got %v, want %v", tt.cmd.Name(), err, tt.err)
+		}
+
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: cache left behind: %v", tt.cmd.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/table"
+
+	"endobit.io/metal-cli/internal/config"
+)
+
+// Config manages the configuration file. File and Context point at the
+// root command's --config and --context flags.
+type Config struct {
+	File    *string
+	Context *string
+}
+
+func (c *Config) New() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "config",
+		Short: "Manage client configuration",
+		Long: "Manage named contexts in the configuration file.\n\n" +
+			"Settings are taken from command line flags first, then STACK_* environment\n" +
+			"variables (STACK_SERVER, STACK_ZONE, ...), then the selected context.",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "get-contexts",
+			Short: "List the contexts",
+			Args:  cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return c.getContexts()
+			},
+		},
+		&cobra.Command{
+			Use:   "use-context name",
+			Short: "Set the current context",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return c.useContext(args[0])
+			},
+		},
+		&cobra.Command{
+			Use:   "set key value",
+			Short: "Set a context's properties",
+			Long: "Set a property of the --context context, or the current context.\n" +
+				"The context is created if it does not exist.\n\n" +
+				"Keys are " + strings.Join(config.Keys, ", ") + ".",
+			Args: cobra.ExactArgs(2),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return c.set(args[0], args[1])
+			},
+		})
+
+	for _, sub := range cmd.Commands() {
+		sub.Annotations = map[string]string{SkipAuth: Offline}
+	}
+
+	return &cmd
+}
+
+func (c *Config) getContexts() error {
+	type row struct{ Current, Context, Server, Username, Zone, Cluster string }
+	t := table.New()
+	defer t.Flush()
+
+	cfg, err := config.Load(Val(c.File))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range cfg.Names() {
+		ctx := cfg.Contexts[name]
+
+		var current string
+		if name == cfg.CurrentContext {
+			current = "*"
+		}
+
+		_ = t.Write(row{
+			Current:  current,
+			Context:  name,
+			Server:   ctx.Server,
+			Username: ctx.Username,
+			Zone:     ctx.Zone,
+			Cluster:  ctx.Cluster,
+		})
+	}
+
+	return nil
+}
+
+func (c *Config) useContext(name string) error {
+	cfg, err := config.Load(Val(c.File))
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.UseContext(name); err != nil {
+		return err
+	}
+
+	return cfg.Save()
+}
+
+func (c *Config) set(key, value string) error {
+	cfg, err := config.Load(Val(c.File))
+	if err != nil {
+		return err
+	}
+
+	name := Val(c.Context)
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+
+	if err := cfg.Set(name, key, value); err != nil {
+		return err
+	}
+
+	return cfg.Save()
+}
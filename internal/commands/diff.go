@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal-cli/internal/diff"
+	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/protoyaml"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// ErrDiffer is returned by diff when the schemas differ, which is an answer
+// rather than a failure: like diff(1), diff exits 1 for it and 2 on errors.
+var ErrDiffer = errors.New("schemas differ")
+
+// diffStatus is the annotation for commands that exit like diff(1).
+const diffStatus = "diff-status"
+
+// ExitCode is the exit status for err returned by cmd. It is 1, except for
+// commands that exit like diff(1), where 1 is kept for differences and errors
+// are 2.
+func ExitCode(cmd *cobra.Command, err error) int {
+	if _, ok := cmd.Annotations[diffStatus]; ok && !errors.Is(err, ErrDiffer) {
+		return 2
+	}
+
+	return 1
+}
+
+type diffFlags struct {
+	output flags.DiffFormat
+	format flags.Format
+}
+
+func (r *Root) diff(ctx context.Context, a, b string, f *diffFlags) error {
+	if format := f.output.Val(); !slices.Contains(diff.Formats, format) {
+		return fmt.Errorf("unknown output format %q, want one of %s", format, strings.Join(diff.Formats, ", "))
+	}
+
+	if a == "-" && b == "-" {
+		return errors.New("only one side can be read from stdin")
+	}
+
+	from, err := r.readSide(ctx, a, f.format.Val())
+	if err != nil {
+		return err
+	}
+
+	to, err := r.readSide(ctx, b, f.format.Val())
+	if err != nil {
+		return err
+	}
+
+	changes, err := schemaDiff(from, to, true)
+	if err != nil {
+		return err
+	}
+
+	if f.output.Val() == "patch" {
+		err = patch(from, to, a, b)
+	} else {
+		err = diff.Write(os.Stdout, f.output.Val(), changes, useColor(os.Stdout))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(changes) > 0 {
+		return ErrDiffer
+	}
+
+	return nil
+}
+
+// patch writes a unified diff of the YAML dumps of two schemas, as dump
+// writes them, so it applies to a dump with patch(1).
+func patch(from, to *pb.Schema, a, b string) error {
+	x, err := protoyaml.Marshal(from, nil)
+	if err != nil {
+		return err
+	}
+
+	y, err := protoyaml.Marshal(to, nil)
+	if err != nil {
+		return err
+	}
+
+	return diff.Patch(os.Stdout, x, y, a, b, useColor(os.Stdout))
+}
+
+// readSide reads one side of a diff, a schema file or a context's server.
+func (r *Root) readSide(ctx context.Context, side, format string) (*pb.Schema, error) {
+	name, ok := strings.CutPrefix(side, "@")
+	if !ok {
+		return readSchema([]string{side}, format)
+	}
+
+	client, err := r.Connect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Metal.ReadSchema(client.Context(), &pb.ReadSchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetSchema(), nil
+}
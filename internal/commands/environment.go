@@ -4,23 +4,27 @@ import (
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
-	"endobit.io/table"
 
 	"endobit.io/metal-cli/internal/flags"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
 )
 
 type Environment struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
 }
 
 type EnvironmentAttr struct {
 	Client          *metal.Client
+	listFlags       *listFlags
+	removeFlags     *removeFlags
 	renameFlag      flags.Rename
 	zoneFlag        flags.Zone
 	environmentFlag flags.Environment
+	valueFlags      attrValueFlags
 }
 
 func (e *Environment) New(verb Verb) *cobra.Command {
@@ -84,7 +88,7 @@ func (e *Environment) New(verb Verb) *cobra.Command {
 		e.renameFlag.Add(cmd.Flags(), environment)
 	}
 
-	attr := EnvironmentAttr{Client: e.Client}
+	attr := EnvironmentAttr{Client: e.Client, listFlags: e.listFlags, removeFlags: e.removeFlags}
 	cmd.AddCommand(attr.New(verb))
 
 	return &cmd
@@ -103,8 +107,10 @@ func (e *Environment) create(environment string) error {
 
 func (e *Environment) list(glob string) error {
 	type row struct{ Zone, Environment string }
-	t := table.New()
-	defer t.Flush()
+	t, err := e.listFlags.newWriter(e.Client, environment)
+	if err != nil {
+		return err
+	}
 
 	r := e.Client.NewEnvironmentReader(e.zoneFlag.Val(), glob)
 
@@ -116,10 +122,10 @@ func (e *Environment) list(glob string) error {
 		_ = t.Write(row{
 			Zone:        resp.GetZone(),
 			Environment: resp.GetName(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (e *Environment) update(environment string) error {
@@ -137,14 +143,18 @@ func (e *Environment) update(environment string) error {
 }
 
 func (e *Environment) remove(glob string) error {
-	req := pb.DeleteEnvironmentsRequest_builder{
-		Zone: e.zoneFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := e.Client.NewEnvironmentReader(e.zoneFlag.Val(), glob).Responses()
 
-	_, err := e.Client.Metal.DeleteEnvironments(e.Client.Context(), req)
+	return removeMatches(e.removeFlags, environment, glob, matches, nil, func(name string) error {
+		req := pb.DeleteEnvironmentsRequest_builder{
+			Zone: e.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := e.Client.Metal.DeleteEnvironments(e.Client.Context(), req)
+
+		return err
+	})
 }
 
 func (e *EnvironmentAttr) New(verb Verb) *cobra.Command {
@@ -206,6 +216,10 @@ func (e *EnvironmentAttr) New(verb Verb) *cobra.Command {
 		e.environmentFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		e.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		e.renameFlag.Add(cmd.Flags(), environment)
 	}
@@ -226,9 +240,14 @@ func (e *EnvironmentAttr) create(attr string) error {
 }
 
 func (e *EnvironmentAttr) list(glob string) error {
-	type row struct{ Zone, Environment, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Zone, Environment, Attr string
+		Value                   attrText
+	}
+	t, err := e.listFlags.newWriter(e.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := e.Client.NewEnvironmentAttrReader(e.zoneFlag.Val(), e.environmentFlag.Val(), glob)
 
@@ -241,36 +260,46 @@ func (e *EnvironmentAttr) list(glob string) error {
 			Zone:        resp.GetZone(),
 			Environment: resp.GetEnvironment(),
 			Attr:        resp.GetName(),
-			Value:       resp.GetValue(),
-		})
+			Value:       attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (e *EnvironmentAttr) update(attr string) error {
+	value, err := e.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateEnvironmentAttrRequest_builder{
 		Zone:        e.zoneFlag.Ptr(),
 		Environment: e.environmentFlag.Ptr(),
 		Name:        &attr,
 		Fields: pb.UpdateEnvironmentAttrRequest_Fields_builder{
-			Name: e.renameFlag.Ptr(),
+			Name:  e.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := e.Client.Metal.UpdateEnvironmentAttr(e.Client.Context(), req)
+	_, err = e.Client.Metal.UpdateEnvironmentAttr(e.Client.Context(), req)
 
 	return err
 }
 
 func (e *EnvironmentAttr) remove(glob string) error {
-	req := pb.DeleteEnvironmentAttrsRequest_builder{
-		Zone:        e.zoneFlag.Ptr(),
-		Environment: e.environmentFlag.Ptr(),
-		Glob:        &glob,
-	}.Build()
+	matches := e.Client.NewEnvironmentAttrReader(e.zoneFlag.Val(), e.environmentFlag.Val(), glob).Responses()
 
-	_, err := e.Client.Metal.DeleteEnvironmentAttrs(e.Client.Context(), req)
+	return removeMatches(e.removeFlags, environment+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteEnvironmentAttrsRequest_builder{
+			Zone:        e.zoneFlag.Ptr(),
+			Environment: e.environmentFlag.Ptr(),
+			Glob:        &name,
+		}.Build()
 
-	return err
+		_, err := e.Client.Metal.DeleteEnvironmentAttrs(e.Client.Context(), req)
+
+		return err
+	})
 }
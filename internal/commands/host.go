@@ -0,0 +1,424 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal"
+
+	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/report"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+type Host struct {
+	Client          *metal.Client
+	listFlags       *listFlags
+	removeFlags     *removeFlags
+	renameFlag      flags.Rename
+	zoneFlag        flags.Zone
+	clusterFlag     flags.Cluster
+	environmentFlag flags.Environment
+	applianceFlag   flags.Appliance
+	modelFlag       flags.Model
+	rackFlag        flags.Rack
+	templateFlag    flags.Template
+}
+
+type HostInterface struct {
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
+	hostFlag    flags.Host
+	macFlag     flags.MAC
+	ipFlag      flags.IP
+	networkFlag flags.Network
+	netmaskFlag flags.Netmask
+	vlanFlag    flags.VLAN
+	bootFlag    flags.Boot
+	primaryFlag flags.Primary
+}
+
+func (h *Host) New(verb Verb) *cobra.Command {
+	var cmd cobra.Command
+
+	switch verb {
+	case Add:
+		cmd = cobra.Command{
+			Use:   host + " name",
+			Short: "Add a " + host + " to a zone",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if err := h.create(args[0]); err != nil {
+					return err
+				}
+
+				return h.update(args[0])
+			},
+		}
+	case Set:
+		cmd = cobra.Command{
+			Use:   host + " name",
+			Short: "Set a " + host + "'s properties",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return h.update(args[0])
+			},
+		}
+	case List:
+		cmd = cobra.Command{
+			Use:   host + " [glob]",
+			Short: "List one or more " + host + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return h.list(glob)
+			},
+		}
+	case Remove:
+		cmd = cobra.Command{
+			Use:   host + " glob",
+			Short: "Remove one or more " + host + "s",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return h.remove(args[0])
+			},
+		}
+	case Report:
+		cmd = cobra.Command{
+			Use:   host + " [glob]",
+			Short: "Report one or more " + host + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return h.report(glob)
+			},
+		}
+	}
+
+	h.zoneFlag.Add(cmd.Flags(), host)
+
+	if verb == Add || verb == Set || verb == Remove {
+		h.zoneFlag.Required(cmd.Flags())
+	}
+
+	if verb == Add || verb == Set {
+		h.clusterFlag.Add(cmd.Flags(), host)
+		h.environmentFlag.Add(cmd.Flags(), host)
+		h.applianceFlag.Add(cmd.Flags(), host)
+		h.modelFlag.Add(cmd.Flags(), host)
+		h.rackFlag.Add(cmd.Flags(), host)
+	}
+
+	if verb == Set {
+		h.renameFlag.Add(cmd.Flags(), host)
+	}
+
+	if verb == Report {
+		h.templateFlag.Add(cmd.Flags(), host)
+		h.templateFlag.Required(cmd.Flags())
+
+		return &cmd
+	}
+
+	nic := HostInterface{Client: h.Client, listFlags: h.listFlags, removeFlags: h.removeFlags}
+	cmd.AddCommand(nic.New(verb))
+
+	return &cmd
+}
+
+func (h *Host) create(host string) error {
+	req := pb.CreateHostRequest_builder{
+		Zone: h.zoneFlag.Ptr(),
+		Name: &host,
+	}.Build()
+
+	_, err := h.Client.Metal.CreateHost(h.Client.Context(), req)
+
+	return err
+}
+
+func (h *Host) list(glob string) error {
+	type row struct{ Zone, Host, Cluster, Environment, Appliance, Model, Rack string }
+	t, err := h.listFlags.newWriter(h.Client, host)
+	if err != nil {
+		return err
+	}
+
+	r := h.Client.NewHostReader(h.zoneFlag.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Zone:        resp.GetZone(),
+			Host:        resp.GetName(),
+			Cluster:     resp.GetCluster(),
+			Environment: resp.GetEnvironment(),
+			Appliance:   resp.GetAppliance(),
+			Model:       resp.GetModel(),
+			Rack:        resp.GetRack(),
+		}, resp)
+	}
+
+	return t.Flush()
+}
+
+func (h *Host) report(glob string) error {
+	hosts, err := collect(h.Client.NewHostReader(h.zoneFlag.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	r := report.Renderer{Client: h.Client, Object: host}
+
+	return r.Render(os.Stdout, h.templateFlag.Val(), hosts)
+}
+
+func (h *Host) update(host string) error {
+	req := pb.UpdateHostRequest_builder{
+		Zone: h.zoneFlag.Ptr(),
+		Name: &host,
+		Fields: pb.UpdateHostRequest_Fields_builder{
+			Name:        h.renameFlag.Ptr(),
+			Cluster:     h.clusterFlag.Ptr(),
+			Environment: h.environmentFlag.Ptr(),
+			Appliance:   h.applianceFlag.Ptr(),
+			Model:       h.modelFlag.Ptr(),
+			Rack:        h.rackFlag.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := h.Client.Metal.UpdateHost(h.Client.Context(), req)
+
+	return err
+}
+
+func (h *Host) remove(glob string) error {
+	matches := h.Client.NewHostReader(h.zoneFlag.Val(), glob).Responses()
+
+	return removeMatches(h.removeFlags, host, glob, matches, nil, func(name string) error {
+		req := pb.DeleteHostsRequest_builder{
+			Zone: h.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
+
+		_, err := h.Client.Metal.DeleteHosts(h.Client.Context(), req)
+
+		return err
+	})
+}
+
+func (i *HostInterface) New(verb Verb) *cobra.Command {
+	var cmd cobra.Command
+
+	switch verb {
+	case Add:
+		cmd = cobra.Command{
+			Use:   iface + " name",
+			Short: "Add an " + iface + " to a " + host,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if err := i.create(args[0]); err != nil {
+					return err
+				}
+
+				return i.update(args[0])
+			},
+		}
+	case Set:
+		cmd = cobra.Command{
+			Use:   iface + " name",
+			Short: "Set a " + host + " " + iface + "'s properties",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return i.update(args[0])
+			},
+		}
+	case List:
+		cmd = cobra.Command{
+			Use:   iface + " [glob]",
+			Short: "List one or more " + host + " " + iface + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return i.list(glob)
+			},
+		}
+	case Remove:
+		cmd = cobra.Command{
+			Use:   iface + " glob",
+			Short: "Remove one or more " + host + " " + iface + "s",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return i.remove(args[0])
+			},
+		}
+	}
+
+	i.zoneFlag.Add(cmd.Flags(), host)
+	i.hostFlag.Add(cmd.Flags(), iface)
+
+	if verb == Add || verb == Set || verb == Remove {
+		i.zoneFlag.Required(cmd.Flags())
+		i.hostFlag.Required(cmd.Flags())
+	}
+
+	if verb == Add || verb == Set {
+		i.macFlag.Add(cmd.Flags(), iface)
+		i.ipFlag.Add(cmd.Flags(), iface)
+		i.networkFlag.Add(cmd.Flags(), iface)
+		i.netmaskFlag.Add(cmd.Flags(), iface)
+		i.vlanFlag.Add(cmd.Flags(), iface)
+		i.bootFlag.Add(cmd.Flags(), iface)
+		i.primaryFlag.Add(cmd.Flags(), iface)
+
+		cmd.PreRunE = func(_ *cobra.Command, _ []string) error {
+			return i.validate()
+		}
+	}
+
+	if verb == Set {
+		i.renameFlag.Add(cmd.Flags(), iface)
+	}
+
+	return &cmd
+}
+
+func (i *HostInterface) create(nic string) error {
+	req := pb.CreateHostInterfaceRequest_builder{
+		Zone: i.zoneFlag.Ptr(),
+		Host: i.hostFlag.Ptr(),
+		Name: &nic,
+	}.Build()
+
+	_, err := i.Client.Metal.CreateHostInterface(i.Client.Context(), req)
+
+	return err
+}
+
+func (i *HostInterface) list(glob string) error {
+	type row struct {
+		Zone, Host, Interface, MAC, IP, Network string
+		Netmask                                 string `output:"wide"`
+		VLAN                                    uint32 `output:"wide"`
+		Boot, Primary                           bool
+	}
+	t, err := i.listFlags.newWriter(i.Client, iface)
+	if err != nil {
+		return err
+	}
+
+	r := i.Client.NewHostInterfaceReader(i.zoneFlag.Val(), i.hostFlag.Val(), glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Zone:      resp.GetZone(),
+			Host:      resp.GetHost(),
+			Interface: resp.GetName(),
+			MAC:       resp.GetMac(),
+			IP:        resp.GetIp(),
+			Network:   resp.GetNetwork(),
+			Netmask:   resp.GetNetmask(),
+			VLAN:      resp.GetVlan(),
+			Boot:      resp.GetBoot(),
+			Primary:   resp.GetPrimary(),
+		}, resp)
+	}
+
+	return t.Flush()
+}
+
+func (i *HostInterface) update(nic string) error {
+	req := pb.UpdateHostInterfaceRequest_builder{
+		Zone: i.zoneFlag.Ptr(),
+		Host: i.hostFlag.Ptr(),
+		Name: &nic,
+		Fields: pb.UpdateHostInterfaceRequest_Fields_builder{
+			Name:    i.renameFlag.Ptr(),
+			Mac:     i.macFlag.Ptr(),
+			Ip:      i.ipFlag.Ptr(),
+			Network: i.networkFlag.Ptr(),
+			Netmask: i.netmaskFlag.Ptr(),
+			Vlan:    i.vlanFlag.Ptr(),
+			Boot:    i.bootFlag.Ptr(),
+			Primary: i.primaryFlag.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := i.Client.Metal.UpdateHostInterface(i.Client.Context(), req)
+
+	return err
+}
+
+// validate catches malformed addresses before they reach the server.
+func (i *HostInterface) validate() error {
+	if mac := i.macFlag.Val(); mac != "" {
+		if _, err := net.ParseMAC(mac); err != nil {
+			return err
+		}
+	}
+
+	if ip := i.ipFlag.Val(); ip != "" {
+		if _, err := netip.ParseAddr(ip); err != nil {
+			return err
+		}
+	}
+
+	if mask := i.netmaskFlag.Val(); mask != "" {
+		addr, err := netip.ParseAddr(mask)
+		if err != nil {
+			return err
+		}
+
+		if ones, bits := net.IPMask(addr.AsSlice()).Size(); ones == 0 && bits == 0 {
+			return fmt.Errorf("invalid netmask %q", mask)
+		}
+	}
+
+	if vlan := i.vlanFlag.Val(); vlan > 4094 {
+		return fmt.Errorf("invalid VLAN %d", vlan)
+	}
+
+	return nil
+}
+
+func (i *HostInterface) remove(glob string) error {
+	matches := i.Client.NewHostInterfaceReader(i.zoneFlag.Val(), i.hostFlag.Val(), glob).Responses()
+
+	return removeMatches(i.removeFlags, host+" "+iface, glob, matches, nil, func(name string) error {
+		req := pb.DeleteHostInterfacesRequest_builder{
+			Zone: i.zoneFlag.Ptr(),
+			Host: i.hostFlag.Ptr(),
+			Glob: &name,
+		}.Build()
+
+		_, err := i.Client.Metal.DeleteHostInterfaces(i.Client.Context(), req)
+
+		return err
+	})
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"endobit.io/metal"
+
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// updateHostClient records the UpdateHost request instead of sending it.
+type updateHostClient struct {
+	pb.MetalServiceClient
+	req *pb.UpdateHostRequest
+}
+
+func (c *updateHostClient) UpdateHost(_ context.Context, req *pb.UpdateHostRequest, _ ...grpc.CallOption) (*pb.UpdateHostResponse, error) {
+	c.req = req
+
+	return nil, nil
+}
+
+// TestHostSet checks set sends only the fields given on the command line, so
+// the others are left unchanged, and sends a field given as "" to clear it.
+func TestHostSet(t *testing.T) {
+	var rpc updateHostClient
+
+	h := Host{Client: &metal.Client{Metal: &rpc}}
+
+	cmd := h.New(Set)
+	cmd.SetArgs([]string{"--zone", "east", "--rack", "", "--model", "r640", "h1"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rpc.req == nil {
+		t.Fatal("UpdateHost was not called")
+	}
+
+	fields := rpc.req.GetFields()
+
+	if !fields.HasRack() || fields.GetRack() != "" {
+		t.Error("rack is not sent empty to clear it")
+	}
+
+	if !fields.HasModel() || fields.GetModel() != "r640" {
+		t.Errorf("model = %q, want r640", fields.GetModel())
+	}
+
+	for name, has := range map[string]bool{
+		"name":        fields.HasName(),
+		"cluster":     fields.HasCluster(),
+		"environment": fields.HasEnvironment(),
+		"appliance":   fields.HasAppliance(),
+	} {
+		if has {
+			t.Errorf("%s is sent, but was not given", name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"endobit.io/metal-cli/internal/lint"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+func (r *Root) lint(names []string) error {
+	l := lint.New((&pb.Schema{}).ProtoReflect().Descriptor())
+
+	// Included files are linted through their include, in the object holding
+	// it, and not again as schemas of their own.
+	files, err := rootFiles(names)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		var data []byte
+
+		if file == "-" {
+			file = "<stdin>"
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		l.Lint(file, data)
+	}
+
+	problems := l.Problems()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("1 problem")
+	}
+
+	return fmt.Errorf("%d problems", len(problems))
+}
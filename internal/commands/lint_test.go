@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLintInclude lints a directory holding a file and the fragment it
+// includes, which fits the object holding the include but not the schema,
+// so it must only be linted through the include.
+func TestLintInclude(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"site.yaml":     "zones:\n- name: east\n  racks:\n  - $include: racks/r1.yaml\n",
+		"racks/r1.yaml": "name: r1\n",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var r Root
+
+	if err := r.lint([]string{dir}); err != nil {
+		t.Errorf("lint %s: %v", dir, err)
+	}
+
+	// Linted on its own, the fragment is not a schema.
+	if err := r.lint([]string{filepath.Join(dir, "racks", "r1.yaml")}); err == nil {
+		t.Error("linted a rack as a schema without problems")
+	}
+}
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal"
+
+	"endobit.io/metal-cli/internal/flags"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+type Make struct {
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+}
+
+func (m *Make) New(verb Verb) *cobra.Command {
+	var cmd cobra.Command
+
+	switch verb {
+	case Add:
+		cmd = cobra.Command{
+			Use:   vendor + " name",
+			Short: "Add a " + vendor,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if err := m.create(args[0]); err != nil {
+					return err
+				}
+
+				return m.update(args[0])
+			},
+		}
+	case Set:
+		cmd = cobra.Command{
+			Use:   vendor + " name",
+			Short: "Set a " + vendor + "'s properties",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return m.update(args[0])
+			},
+		}
+	case List:
+		cmd = cobra.Command{
+			Use:   vendor + " [glob]",
+			Short: "List one or more " + vendor + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return m.list(glob)
+			},
+		}
+	case Remove:
+		cmd = cobra.Command{
+			Use:   vendor + " glob",
+			Short: "Remove one or more " + vendor + "s",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return m.remove(args[0])
+			},
+		}
+	}
+
+	if verb == Set {
+		m.renameFlag.Add(cmd.Flags(), vendor)
+	}
+
+	return &cmd
+}
+
+func (m *Make) create(vendor string) error {
+	var req pb.CreateMakeRequest
+
+	req.SetName(vendor)
+	_, err := m.Client.Metal.CreateMake(m.Client.Context(), &req)
+
+	return err
+}
+
+func (m *Make) list(glob string) error {
+	type row struct {
+		Make   string
+		Models int
+	}
+	t, err := m.listFlags.newWriter(m.Client, vendor)
+	if err != nil {
+		return err
+	}
+
+	// Count the models of every make in one read, not one per make.
+	models := make(map[string]int)
+
+	for resp, err := range m.Client.NewModelReader("", "").Responses() {
+		if err != nil {
+			return err
+		}
+
+		models[resp.GetMake()]++
+	}
+
+	r := m.Client.NewMakeReader(glob)
+
+	for resp, err := range r.Responses() {
+		if err != nil {
+			return err
+		}
+
+		_ = t.Write(row{
+			Make:   resp.GetName(),
+			Models: models[resp.GetName()],
+		}, resp)
+	}
+
+	return t.Flush()
+}
+
+func (m *Make) update(vendor string) error {
+	req := pb.UpdateMakeRequest_builder{
+		Name: &vendor,
+		Fields: pb.UpdateMakeRequest_Fields_builder{
+			Name: m.renameFlag.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := m.Client.Metal.UpdateMake(m.Client.Context(), req)
+
+	return err
+}
+
+func (m *Make) remove(glob string) error {
+	matches := m.Client.NewMakeReader(glob).Responses()
+	every := m.Client.NewMakeReader("").Responses()
+
+	return removeMatches(m.removeFlags, vendor, glob, matches, every, func(name string) error {
+		var req pb.DeleteMakesRequest
+
+		req.SetGlob(name)
+		_, err := m.Client.Metal.DeleteMakes(m.Client.Context(), &req)
+
+		return err
+	})
+}
@@ -1,26 +1,32 @@
 package commands
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
 	"endobit.io/metal-cli/internal/flags"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
-	"endobit.io/table"
 )
 
 type Model struct {
-	Client     *metal.Client
-	makeFlag   flags.Make
-	archFlag   flags.Arch
-	renameFlag flags.Rename
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	makeFlag    flags.Make
+	archFlag    flags.Arch
+	renameFlag  flags.Rename
 }
 
 type ModelAttr struct {
-	Client     *metal.Client
-	makeFlag   flags.Make
-	renameFlag flags.Rename
-	modelFlag  flags.Model
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	makeFlag    flags.Make
+	renameFlag  flags.Rename
+	modelFlag   flags.Model
+	valueFlags  attrValueFlags
 }
 
 func (m *Model) New(verb Verb) *cobra.Command {
@@ -29,7 +35,7 @@ func (m *Model) New(verb Verb) *cobra.Command {
 	switch verb {
 	case Add:
 		cmd = cobra.Command{
-			Use:   model + "make name",
+			Use:   model + " make name",
 			Short: "Add a " + model,
 			Args:  cobra.ExactArgs(2),
 			RunE: func(_ *cobra.Command, args []string) error {
@@ -42,7 +48,7 @@ func (m *Model) New(verb Verb) *cobra.Command {
 		}
 	case Set:
 		cmd = cobra.Command{
-			Use:   model + "make name",
+			Use:   model + " make name",
 			Short: "Set a " + model + "'s properties",
 			Args:  cobra.ExactArgs(2),
 			RunE: func(_ *cobra.Command, args []string) error {
@@ -51,7 +57,7 @@ func (m *Model) New(verb Verb) *cobra.Command {
 		}
 	case List:
 		cmd = cobra.Command{
-			Use:   model + "make [glob]",
+			Use:   model + " make [glob]",
 			Short: "List one or more " + model + "s",
 			Args:  cobra.RangeArgs(1, 2),
 			RunE: func(_ *cobra.Command, args []string) error {
@@ -66,7 +72,7 @@ func (m *Model) New(verb Verb) *cobra.Command {
 		}
 	case Remove:
 		cmd = cobra.Command{
-			Use:   model + "make glob",
+			Use:   model + " make glob",
 			Short: "Remove one or more " + model + "s",
 			Args:  cobra.ExactArgs(2),
 			RunE: func(_ *cobra.Command, args []string) error {
@@ -78,12 +84,20 @@ func (m *Model) New(verb Verb) *cobra.Command {
 	if verb == Add || verb == Set {
 		m.makeFlag.Add(cmd.Flags(), model)
 		m.archFlag.Add(cmd.Flags(), model)
+
+		cmd.PreRunE = func(_ *cobra.Command, _ []string) error {
+			_, err := m.arch()
+			return err
+		}
 	}
 
 	if verb == Set {
 		m.renameFlag.Add(cmd.Flags(), model)
 	}
 
+	attr := ModelAttr{Client: m.Client, listFlags: m.listFlags, removeFlags: m.removeFlags}
+	cmd.AddCommand(attr.New(verb))
+
 	return &cmd
 }
 
@@ -99,8 +113,10 @@ func (m *Model) create(vendor, model string) error {
 
 func (m *Model) list(vendor, glob string) error {
 	type row struct{ Make, Model, Arch string }
-	t := table.New()
-	defer t.Flush()
+	t, err := m.listFlags.newWriter(m.Client, model)
+	if err != nil {
+		return err
+	}
 
 	r := m.Client.NewModelReader(vendor, glob)
 
@@ -113,18 +129,16 @@ func (m *Model) list(vendor, glob string) error {
 			Make:  resp.GetMake(),
 			Model: resp.GetName(),
 			Arch:  resp.GetArchitecture().String(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (m *Model) update(vendor, model string) error {
-	var pbarch *pb.Architecture
-
-	if m.archFlag.Val() != "" {
-		a := pb.Architecture(pb.Architecture_value[m.archFlag.Val()])
-		pbarch = &a
+	pbarch, err := m.arch()
+	if err != nil {
+		return err
 	}
 
 	req := pb.UpdateModelRequest_builder{
@@ -136,19 +150,45 @@ func (m *Model) update(vendor, model string) error {
 		}.Build(),
 	}.Build()
 
-	_, err := m.Client.Metal.UpdateModel(m.Client.Context(), req)
+	_, err = m.Client.Metal.UpdateModel(m.Client.Context(), req)
 
 	return err
 }
 
+// arch returns the architecture to send, nil if --arch was not given. An
+// --arch "" clears the architecture, setting it back to unspecified.
+func (m *Model) arch() (*pb.Architecture, error) {
+	if !m.archFlag.Changed() {
+		return nil, nil
+	}
+
+	var a pb.Architecture
+
+	if arch := m.archFlag.Val(); arch != "" {
+		n, ok := pb.Architecture_value[arch]
+		if !ok || n == 0 {
+			return nil, fmt.Errorf("unknown architecture %q", arch)
+		}
+
+		a = pb.Architecture(n)
+	}
+
+	return &a, nil
+}
+
 func (m *Model) remove(vendor, glob string) error {
-	req := pb.DeleteModelsRequest_builder{
-		Make: &vendor,
-		Glob: &glob,
-	}.Build()
+	matches := m.Client.NewModelReader(vendor, glob).Responses()
 
-	_, err := m.Client.Metal.DeleteModels(m.Client.Context(), req)
-	return err
+	return removeMatches(m.removeFlags, model, glob, matches, nil, func(name string) error {
+		req := pb.DeleteModelsRequest_builder{
+			Make: &vendor,
+			Glob: &name,
+		}.Build()
+
+		_, err := m.Client.Metal.DeleteModels(m.Client.Context(), req)
+
+		return err
+	})
 }
 
 func (a *ModelAttr) New(verb Verb) *cobra.Command {
@@ -210,6 +250,10 @@ func (a *ModelAttr) New(verb Verb) *cobra.Command {
 		a.modelFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		a.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		a.renameFlag.Add(cmd.Flags(), model)
 	}
@@ -229,9 +273,14 @@ func (a *ModelAttr) create(attr string) error {
 }
 
 func (a *ModelAttr) list(glob string) error {
-	type row struct{ Make, Model, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Make, Model, Attr string
+		Value             attrText
+	}
+	t, err := a.listFlags.newWriter(a.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := a.Client.NewModelAttrReader(a.modelFlag.Val(), glob)
 
@@ -244,34 +293,44 @@ func (a *ModelAttr) list(glob string) error {
 			Make:  resp.GetMake(),
 			Model: resp.GetModel(),
 			Attr:  resp.GetName(),
-			Value: resp.GetValue(),
-		})
+			Value: attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ModelAttr) update(attr string) error {
+	value, err := a.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateModelAttrRequest_builder{
 		Model: a.modelFlag.Ptr(),
 		Name:  &attr,
 		Fields: pb.UpdateModelAttrRequest_Fields_builder{
-			Name: a.renameFlag.Ptr(),
+			Name:  a.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := a.Client.Metal.UpdateModelAttr(a.Client.Context(), req)
+	_, err = a.Client.Metal.UpdateModelAttr(a.Client.Context(), req)
 
 	return err
 }
 
 func (a *ModelAttr) remove(glob string) error {
-	req := pb.DeleteModelAttrsRequest_builder{
-		Model: a.modelFlag.Ptr(),
-		Glob:  &glob,
-	}.Build()
+	matches := a.Client.NewModelAttrReader(a.modelFlag.Val(), glob).Responses()
 
-	_, err := a.Client.Metal.DeleteModelAttrs(a.Client.Context(), req)
+	return removeMatches(a.removeFlags, model+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteModelAttrsRequest_builder{
+			Model: a.modelFlag.Ptr(),
+			Glob:  &name,
+		}.Build()
 
-	return err
+		_, err := a.Client.Metal.DeleteModelAttrs(a.Client.Context(), req)
+
+		return err
+	})
 }
@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal"
+
+	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/report"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+type Network struct {
+	Client       *metal.Client
+	listFlags    *listFlags
+	removeFlags  *removeFlags
+	renameFlag   flags.Rename
+	zoneFlag     flags.Zone
+	addressFlag  flags.Address
+	gatewayFlag  flags.Gateway
+	dnsFlag      flags.DNS
+	mtuFlag      flags.MTU
+	pxeFlag      flags.PXE
+	templateFlag flags.Template
+}
+
+func (n *Network) New(verb Verb) *cobra.Command {
+	var cmd cobra.Command
+
+	switch verb {
+	case Add:
+		cmd = cobra.Command{
+			Use:   network + " name",
+			Short: "Add a " + network + " to a zone",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				if err := n.create(args[0]); err != nil {
+					return err
+				}
+
+				return n.update(args[0])
+			},
+		}
+	case Set:
+		cmd = cobra.Command{
+			Use:   network + " name",
+			Short: "Set a " + network + "'s properties",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return n.update(args[0])
+			},
+		}
+	case List:
+		cmd = cobra.Command{
+			Use:   network + " [glob]",
+			Short: "List one or more " + network + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return n.list(glob)
+			},
+		}
+	case Remove:
+		cmd = cobra.Command{
+			Use:   network + " glob",
+			Short: "Remove one or more " + network + "s",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return n.remove(args[0])
+			},
+		}
+	case Report:
+		cmd = cobra.Command{
+			Use:   network + " [glob]",
+			Short: "Report one or more " + network + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return n.report(glob)
+			},
+		}
+	}
+
+	n.zoneFlag.Add(cmd.Flags(), network)
+
+	if verb == Add || verb == Set || verb == Remove {
+		n.zoneFlag.Required(cmd.Flags())
+	}
+
+	if verb == Add || verb == Set {
+		n.addressFlag.Add(cmd.Flags(), network)
+		n.gatewayFlag.Add(cmd.Flags(), network)
+		n.dnsFlag.Add(cmd.Flags(), network)
+		n.mtuFlag.Add(cmd.Flags(), network)
+		n.pxeFlag.Add(cmd.Flags(), network)
+
+		cmd.PreRunE = func(_ *cobra.Command, args []string) error {
+			return n.validate(args[0])
+		}
+	}
+
+	if verb == Set {
+		n.renameFlag.Add(cmd.Flags(), network)
+	}
+
+	if verb == Report {
+		n.templateFlag.Add(cmd.Flags(), network)
+		n.templateFlag.Required(cmd.Flags())
+	}
+
+	return &cmd
+}
+
+func (n *Network) create(network string) error {
+	req := pb.CreateNetworkRequest_builder{
+		Zone: n.zoneFlag.Ptr(),
+		Name: &network,
+	}.Build()
+
+	_, err := n.Client.Metal.CreateNetwork(n.Client.Context(), req)
+
+	return err
+}
+
+func (n *Network) list(glob string) error {
+	type row struct {
+		Zone, Network, Address, Gateway string
+		DNS                             string `output:"wide"`
+		MTU                             uint32 `output:"wide"`
+		PXE                             bool
+		Used                            int
+	}
+	t, err := n.listFlags.newWriter(n.Client, network)
+	if err != nil {
+		return err
+	}
+
+	networks, err := collect(n.Client.NewNetworkReader(n.zoneFlag.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	// Interfaces are counted only in the zones of the networks listed, so a
+	// narrow glob does not read every interface.
+	used := make(map[[2]string]int) // {zone, network} -> interfaces
+	zones := make(map[string]bool)
+
+	for _, resp := range networks {
+		if zones[resp.GetZone()] {
+			continue
+		}
+
+		zones[resp.GetZone()] = true
+
+		for resp, err := range n.Client.NewHostInterfaceReader(resp.GetZone(), "", "").Responses() {
+			if err != nil {
+				return err
+			}
+
+			if resp.GetNetwork() != "" {
+				used[[2]string{resp.GetZone(), resp.GetNetwork()}]++
+			}
+		}
+	}
+
+	for _, resp := range networks {
+		_ = t.Write(row{
+			Zone:    resp.GetZone(),
+			Network: resp.GetName(),
+			Address: resp.GetAddress(),
+			Gateway: resp.GetGateway(),
+			DNS:     resp.GetDns(),
+			MTU:     resp.GetMtu(),
+			PXE:     resp.GetPxe(),
+			Used:    used[[2]string{resp.GetZone(), resp.GetName()}],
+		}, resp)
+	}
+
+	return t.Flush()
+}
+
+func (n *Network) report(glob string) error {
+	networks, err := collect(n.Client.NewNetworkReader(n.zoneFlag.Val(), glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	r := report.Renderer{Client: n.Client, Object: network}
+
+	return r.Render(os.Stdout, n.templateFlag.Val(), networks)
+}
+
+func (n *Network) update(network string) error {
+	req := pb.UpdateNetworkRequest_builder{
+		Zone: n.zoneFlag.Ptr(),
+		Name: &network,
+		Fields: pb.UpdateNetworkRequest_Fields_builder{
+			Name:    n.renameFlag.Ptr(),
+			Address: n.addressFlag.Ptr(),
+			Gateway: n.gatewayFlag.Ptr(),
+			Dns:     n.dnsFlag.Ptr(),
+			Mtu:     n.mtuFlag.Ptr(),
+			Pxe:     n.pxeFlag.Ptr(),
+		}.Build(),
+	}.Build()
+
+	_, err := n.Client.Metal.UpdateNetwork(n.Client.Context(), req)
+
+	return err
+}
+
+func (n *Network) remove(glob string) error {
+	matches := n.Client.NewNetworkReader(n.zoneFlag.Val(), glob).Responses()
+
+	return removeMatches(n.removeFlags, network, glob, matches, nil, func(name string) error {
+		req := pb.DeleteNetworksRequest_builder{
+			Zone: n.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
+
+		_, err := n.Client.Metal.DeleteNetworks(n.Client.Context(), req)
+
+		return err
+	})
+}
+
+// validate checks the address fields locally and rejects an address that
+// overlaps another network in the same zone, so a bad range never reaches
+// the server. A gateway given alone is checked against the address the
+// network already has, and an address given alone against its gateway.
+func (n *Network) validate(network string) error {
+	var (
+		prefix netip.Prefix
+		gw     netip.Addr
+	)
+
+	if s := n.addressFlag.Val(); s != "" {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return err
+		}
+
+		if p != p.Masked() {
+			return fmt.Errorf("address %s has host bits set, did you mean %s", p, p.Masked())
+		}
+
+		prefix = p
+	}
+
+	if s := n.gatewayFlag.Val(); s != "" {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return err
+		}
+
+		gw = addr
+	}
+
+	if s := n.dnsFlag.Val(); s != "" {
+		if _, err := netip.ParseAddr(s); err != nil {
+			return err
+		}
+	}
+
+	if mtu := n.mtuFlag.Val(); mtu != 0 && (mtu < 68 || mtu > 65535) {
+		return fmt.Errorf("invalid MTU %d", mtu)
+	}
+
+	if !prefix.IsValid() && !gw.IsValid() {
+		return nil
+	}
+
+	subnet := prefix
+
+	for resp, err := range n.Client.NewNetworkReader(n.zoneFlag.Val(), "").Responses() {
+		if err != nil {
+			return err
+		}
+
+		if resp.GetName() == network {
+			if !subnet.IsValid() {
+				subnet, _ = netip.ParsePrefix(resp.GetAddress()) // invalid when unset
+			}
+
+			if !n.gatewayFlag.Changed() {
+				gw, _ = netip.ParseAddr(resp.GetGateway())
+			}
+
+			continue
+		}
+
+		other, err := netip.ParsePrefix(resp.GetAddress())
+		if err != nil {
+			continue // no address assigned
+		}
+
+		if prefix.IsValid() && prefix.Overlaps(other) {
+			return fmt.Errorf("address %s overlaps %s network %s", prefix, resp.GetName(), other)
+		}
+	}
+
+	if gw.IsValid() && subnet.IsValid() && !subnet.Contains(gw) {
+		return fmt.Errorf("gateway %s is not in %s", gw, subnet)
+	}
+
+	return nil
+}
@@ -4,23 +4,27 @@ import (
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
-	"endobit.io/table"
 
 	"endobit.io/metal-cli/internal/flags"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
 )
 
 type Rack struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
 }
 
 type RackAttr struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
-	rackFlag   flags.Rack
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
+	rackFlag    flags.Rack
+	valueFlags  attrValueFlags
 }
 
 func (e *Rack) New(verb Verb) *cobra.Command {
@@ -84,7 +88,7 @@ func (e *Rack) New(verb Verb) *cobra.Command {
 		e.renameFlag.Add(cmd.Flags(), rack)
 	}
 
-	attr := RackAttr{Client: e.Client}
+	attr := RackAttr{Client: e.Client, listFlags: e.listFlags, removeFlags: e.removeFlags}
 	cmd.AddCommand(attr.New(verb))
 
 	return &cmd
@@ -103,8 +107,10 @@ func (e *Rack) create(rack string) error {
 
 func (e *Rack) list(glob string) error {
 	type row struct{ Zone, Rack string }
-	t := table.New()
-	defer t.Flush()
+	t, err := e.listFlags.newWriter(e.Client, rack)
+	if err != nil {
+		return err
+	}
 
 	r := e.Client.NewRackReader(e.zoneFlag.Val(), glob)
 
@@ -116,10 +122,10 @@ func (e *Rack) list(glob string) error {
 		_ = t.Write(row{
 			Zone: resp.GetZone(),
 			Rack: resp.GetName(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (e *Rack) update(rack string) error {
@@ -137,14 +143,18 @@ func (e *Rack) update(rack string) error {
 }
 
 func (e *Rack) remove(glob string) error {
-	req := pb.DeleteRacksRequest_builder{
-		Zone: e.zoneFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := e.Client.NewRackReader(e.zoneFlag.Val(), glob).Responses()
 
-	_, err := e.Client.Metal.DeleteRacks(e.Client.Context(), req)
+	return removeMatches(e.removeFlags, rack, glob, matches, nil, func(name string) error {
+		req := pb.DeleteRacksRequest_builder{
+			Zone: e.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := e.Client.Metal.DeleteRacks(e.Client.Context(), req)
+
+		return err
+	})
 }
 
 func (a *RackAttr) New(verb Verb) *cobra.Command {
@@ -206,6 +216,10 @@ func (a *RackAttr) New(verb Verb) *cobra.Command {
 		a.rackFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		a.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		a.renameFlag.Add(cmd.Flags(), rack)
 	}
@@ -226,9 +240,14 @@ func (a *RackAttr) create(attr string) error {
 }
 
 func (a *RackAttr) list(glob string) error {
-	type row struct{ Zone, Rack, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Zone, Rack, Attr string
+		Value            attrText
+	}
+	t, err := a.listFlags.newWriter(a.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := a.Client.NewRackAttrReader(a.zoneFlag.Val(), a.rackFlag.Val(), glob)
 
@@ -241,36 +260,46 @@ func (a *RackAttr) list(glob string) error {
 			Zone:  resp.GetZone(),
 			Rack:  resp.GetRack(),
 			Attr:  resp.GetName(),
-			Value: resp.GetValue(),
-		})
+			Value: attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *RackAttr) update(attr string) error {
+	value, err := a.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateRackAttrRequest_builder{
 		Zone: a.zoneFlag.Ptr(),
 		Rack: a.rackFlag.Ptr(),
 		Name: &attr,
 		Fields: pb.UpdateRackAttrRequest_Fields_builder{
-			Name: a.renameFlag.Ptr(),
+			Name:  a.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := a.Client.Metal.UpdateRackAttr(a.Client.Context(), req)
+	_, err = a.Client.Metal.UpdateRackAttr(a.Client.Context(), req)
 
 	return err
 }
 
 func (a *RackAttr) remove(glob string) error {
-	req := pb.DeleteRackAttrsRequest_builder{
-		Zone: a.zoneFlag.Ptr(),
-		Rack: a.rackFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := a.Client.NewRackAttrReader(a.zoneFlag.Val(), a.rackFlag.Val(), glob).Responses()
 
-	_, err := a.Client.Metal.DeleteRackAttrs(a.Client.Context(), req)
+	return removeMatches(a.removeFlags, rack+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteRackAttrsRequest_builder{
+			Zone: a.zoneFlag.Ptr(),
+			Rack: a.rackFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := a.Client.Metal.DeleteRackAttrs(a.Client.Context(), req)
+
+		return err
+	})
 }
@@ -1,40 +1,85 @@
 package commands
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/encoding/protojson"
-	"gopkg.in/yaml.v2"
 
 	"endobit.io/metal"
 
+	"endobit.io/metal-cli/internal/backup"
+	"endobit.io/metal-cli/internal/diff"
 	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/protoyaml"
+	"endobit.io/metal-cli/internal/report"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
 )
 
 type Root struct {
-	Client      *metal.Client
-	jsonFlag    flags.JSON
-	zoneFlag    flags.Zone
-	clusterFlag flags.Cluster
-	hostFlag    flags.Host
+	Client *metal.Client
+
+	// Connect returns a client authenticated to a named configuration
+	// context, or to the current one for "".
+	Connect func(ctx context.Context, name string) (*metal.Client, error)
+
+	// Backups returns the backup history of the current context.
+	Backups func() (*backup.Store, error)
+
+	// CompletionDir returns where shell completion caches object names for
+	// the current context.
+	CompletionDir func() (string, error)
+
+	// Resume connects to the current context for shell completion, without
+	// prompting. It is given the command being completed, whose flags cobra
+	// parses only after completion has started.
+	Resume func(cmd *cobra.Command) error
+}
+
+// Verbs share flag names, like --filename for apply and load, so each verb
+// command has its own flags.
+
+type dumpFlags struct {
+	json    flags.JSON
+	zone    flags.Zone
+	cluster flags.Cluster
+	host    flags.Host
+	update  flags.Update
+}
+
+type loadFlags struct {
+	file   flags.File
+	format flags.Format
+	dryRun flags.DryRun
+	apply  flags.Apply
 }
 
 func (r *Root) New(verb Verb) *cobra.Command {
 	var cmd cobra.Command
 
-	appliance := Appliance{Client: r.Client}
-	environment := Environment{Client: r.Client}
-	cluster := Cluster{Client: r.Client}
-	rack := Rack{Client: r.Client}
-	model := Model{Client: r.Client}
-	zone := Zone{Client: r.Client}
+	// The object commands below list and remove read their flags.
+	var (
+		lf listFlags
+		rf = removeFlags{backup: r.backupBefore}
+	)
+
+	attr := GlobalAttr{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	appliance := Appliance{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	environment := Environment{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	cluster := Cluster{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	host := Host{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	vendor := Make{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	rack := Rack{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	model := Model{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	network := Network{Client: r.Client, listFlags: &lf, removeFlags: &rf}
+	zone := Zone{Client: r.Client, listFlags: &lf, removeFlags: &rf}
 
 	switch verb {
 	case Add:
@@ -45,27 +90,189 @@ func (r *Root) New(verb Verb) *cobra.Command {
 		}
 
 		cmd.AddCommand(
+			attr.New(verb),
 			appliance.New(verb),
 			cluster.New(verb),
 			environment.New(verb),
+			host.New(verb),
+			vendor.New(verb),
 			model.New(verb),
+			network.New(verb),
 			rack.New(verb),
 			zone.New(verb))
 
+		r.backupFirst(&cmd)
+
+	case Apply:
+		var f applyFlags
+
+		cmd = cobra.Command{
+			Use:   "apply -f filename...",
+			Short: "Reconcile the server to a schema file",
+			Long: "Apply makes the server match a JSON or YAML schema file with the fewest create,\n" +
+				"update and delete calls, made in dependency order: zones before racks before\n" +
+				"hosts. Objects missing from the file are deleted only with --prune, children\n" +
+				"first, after confirming or straight away with --yes. Fields missing from the\n" +
+				"file are left alone, or cleared with --prune.",
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.apply(&f)
+			},
+		}
+
+		f.file.Add(cmd.Flags(), "schema")
+		f.file.Required(cmd.Flags())
+		f.format.Add(cmd.Flags(), "schema")
+		f.prune.Add(cmd.Flags(), "schema")
+		f.dryRun.Add(cmd.Flags(), "calls")
+		f.yes.Add(cmd.Flags(), "deletes")
+
+	case Backup:
+		var f confirmFlags
+
+		cmd = cobra.Command{
+			Use:   "backup",
+			Short: "Back up the schema",
+			Long: "Backup saves the schema on the server to the context's local backup history,\n" +
+				"as is done before every command that changes the server.\n\n" +
+				"The backup setting of the context picks when: auto before every change, git\n" +
+				"to also commit each backup to a Git repository in the backup directory, or\n" +
+				"off for only on request. The newest backup_keep backups are kept, " + strconv.Itoa(backup.DefaultKeep) + "\n" +
+				"by default, for example\n\n" +
+				"  stack config set backup git\n" +
+				"  stack config set backup_keep 100",
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.backup()
+			},
+		}
+
+		restore := cobra.Command{
+			Use:   "restore backup",
+			Short: "Reconcile the server to a backup",
+			Long: "Restore makes the server match a backup, as apply --prune does with a schema\n" +
+				"file. The changes are shown first and made after confirming, or straight away\n" +
+				"with --yes. With --dry-run they are only shown.",
+			Args: cobra.ExactArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				return r.restore(args[0], &f)
+			},
+		}
+
+		f.yes.Add(restore.Flags(), "changes")
+		f.dryRun.Add(restore.Flags(), "changes")
+
+		cmd.AddCommand(
+			&cobra.Command{
+				Use:         "list",
+				Aliases:     []string{"ls"},
+				Short:       "List the backups",
+				Args:        cobra.NoArgs,
+				Annotations: map[string]string{SkipAuth: Offline},
+				RunE: func(_ *cobra.Command, _ []string) error {
+					return r.listBackups()
+				},
+			},
+			&cobra.Command{
+				Use:         "show [backup]",
+				Short:       "Show a backup, the newest by default",
+				Args:        cobra.MaximumNArgs(1),
+				Annotations: map[string]string{SkipAuth: Offline},
+				RunE: func(_ *cobra.Command, args []string) error {
+					var id string
+
+					if len(args) > 0 {
+						id = args[0]
+					}
+
+					return r.showBackup(id)
+				},
+			},
+			&restore)
+
+	case Diff:
+		var f diffFlags
+
+		cmd = cobra.Command{
+			Use:   "diff A B",
+			Short: "Compare two schemas",
+			// Diff authenticates to each server it reads, which may be none.
+			Annotations: map[string]string{SkipAuth: "", diffStatus: ""},
+			Long: "Diff compares the objects of two schemas and shows what changes A into B.\n\n" +
+				"Each side is a schema file or directory, - for stdin, or @name for the\n" +
+				"server of a configuration context, @ alone for the current one. For example\n" +
+				"what is live but not in git, and what differs between staging and production\n\n" +
+				"  stack diff schema.yaml @\n" +
+				"  stack diff @staging @production -o patch\n\n" +
+				"The patch output is a unified diff of the two sides' dumps, which patch(1)\n" +
+				"applies to the dump of A.\n\n" +
+				"Like diff(1), diff exits 0 if the schemas are the same, 1 if they differ and\n" +
+				"2 on errors.",
+			Args: cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cmd.SilenceUsage = true
+
+				err := r.diff(cmd.Context(), args[0], args[1], &f)
+				if errors.Is(err, ErrDiffer) {
+					cmd.SilenceErrors = true
+				}
+
+				return err
+			},
+		}
+
+		f.output.Add(cmd.Flags(), "changes")
+		f.format.Add(cmd.Flags(), "schema files")
+
 	case Dump:
+		var f dumpFlags
+
 		cmd = cobra.Command{
 			Use:   "dump",
 			Short: "Dump stack schema",
-			Args:  cobra.NoArgs,
+			Long: "Dump writes the schema as YAML, or as JSON with --json. Fields are in schema\n" +
+				"order and objects sorted by name, so dumps of the same data are identical.\n\n" +
+				"With --update the YAML is written to a file instead, keeping the comments\n" +
+				"already in it on the objects they belong to, so a file can be dumped, edited\n" +
+				"and loaded over and over without losing its notes.",
+			Args: cobra.NoArgs,
 			RunE: func(_ *cobra.Command, _ []string) error {
-				return r.dump()
+				return r.dump(&f)
 			},
 		}
 
-		r.jsonFlag.Add(cmd.Flags(), "schema")
-		r.zoneFlag.Add(cmd.Flags(), "schema")
-		r.clusterFlag.Add(cmd.Flags(), "schema")
-		r.hostFlag.Add(cmd.Flags(), "schema")
+		f.json.Add(cmd.Flags(), "schema")
+		f.zone.Add(cmd.Flags(), "schema")
+		f.cluster.Add(cmd.Flags(), "schema")
+		f.host.Add(cmd.Flags(), "schema")
+		f.update.Add(cmd.Flags(), "schema")
+		cmd.MarkFlagsMutuallyExclusive("json", "update")
+
+	case Lint:
+		var file flags.File
+
+		cmd = cobra.Command{
+			Use:   "lint [filename...]",
+			Short: "Check schema files",
+			Long: "Lint checks JSON or YAML schema files, and the files they include, without\n" +
+				"loading them. It reports unknown fields, values of the wrong type, invalid\n" +
+				"enum values, objects named twice and references to objects that are not\n" +
+				"defined, like a host's rack, as file:line:column. Files are read as by load.\n\n" +
+				"Lint exits non-zero if there are any problems.",
+			Annotations: map[string]string{SkipAuth: Offline},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				names := slices.Concat(args, file.Val())
+				if len(names) == 0 {
+					return errors.New("no schema files given")
+				}
+
+				cmd.SilenceUsage = true
+
+				return r.lint(names)
+			},
+		}
+
+		file.Add(cmd.Flags(), "schema")
 
 	case Set:
 		cmd = cobra.Command{
@@ -75,77 +282,191 @@ func (r *Root) New(verb Verb) *cobra.Command {
 		}
 
 		cmd.AddCommand(
+			attr.New(verb),
 			appliance.New(verb),
 			cluster.New(verb),
 			environment.New(verb),
+			host.New(verb),
+			vendor.New(verb),
 			model.New(verb),
+			network.New(verb),
 			rack.New(verb),
 			zone.New(verb))
 
+		r.backupFirst(&cmd)
+
 	case List:
 		cmd = cobra.Command{
 			Use:     "list",
 			Aliases: []string{"ls"},
 			Short:   "List objects",
-			Long:    "List is for humans.",
+			Long: "List is for humans, and for scripts with --output json, yaml, csv, tsv or\n" +
+				"name.\n\n" +
+				"Fields are picked with --columns, or with a kubectl style JSONPath or Go\n" +
+				"template run over {\"items\": [...]}, the objects as the server returns them\n" +
+				"with every field, for example\n\n" +
+				"  stack list rack --zone a -o jsonpath='{.items[*].name}'\n" +
+				"  stack list zone -o go-template='{{range .items}}{{.time_zone}}{{\"\\n\"}}{{end}}'\n\n" +
+				"Rows are picked with --where on columns and attrs, or --selector on attrs,\n" +
+				"and ordered with --sort-by, --reverse and --limit, for example racks in zone\n" +
+				"east without a power attr\n\n" +
+				"  stack list rack --zone east --where '!attr.power'\n" +
+				"  stack list rack --zone east -l '!power'",
 		}
 
+		lf.output.Add(cmd.PersistentFlags(), "objects")
+		lf.columns.Add(cmd.PersistentFlags(), "objects")
+		lf.sortBy.Add(cmd.PersistentFlags(), "objects")
+		lf.reverse.Add(cmd.PersistentFlags(), "objects")
+		lf.limit.Add(cmd.PersistentFlags(), "objects")
+		lf.where.Add(cmd.PersistentFlags(), "objects")
+		lf.selector.Add(cmd.PersistentFlags(), "objects")
+
 		cmd.AddCommand(
+			attr.New(verb),
 			appliance.New(verb),
 			cluster.New(verb),
 			environment.New(verb),
+			host.New(verb),
+			vendor.New(verb),
 			model.New(verb),
+			network.New(verb),
 			rack.New(verb),
 			zone.New(verb))
 
 	case Load:
+		var f loadFlags
+
 		cmd = cobra.Command{
-			Use:     "load filename",
+			Use:     "load [filename...]",
 			Aliases: []string{"ld"},
-			Args:    cobra.ExactArgs(1),
 			Short:   "Load objects",
+			Long: "Load creates and updates the objects in JSON or YAML schema files.\n\n" +
+				"Files are given as arguments or with -f. A directory loads every .json, .yaml\n" +
+				"and .yml file below it and - reads stdin, with --format. The files are merged\n" +
+				"into one schema, objects with the same name are combined. A file can pull in\n" +
+				"others with \"$include: path\" or a list of paths, relative to the file and\n" +
+				"globs allowed, which are merged into the object holding it.\n\n" +
+				"The schema is compared with the schema on the server first and the changes\n" +
+				"are shown. They are applied after confirming, or straight away with --apply.\n" +
+				"Load never removes objects.",
 			RunE: func(_ *cobra.Command, args []string) error {
-				return r.load(args[0])
+				names := slices.Concat(args, f.file.Val())
+				if len(names) == 0 {
+					return errors.New("no schema files given")
+				}
+
+				return r.load(names, &f)
 			},
 		}
 
+		f.file.Add(cmd.Flags(), "schema")
+		f.format.Add(cmd.Flags(), "schema")
+		f.dryRun.Add(cmd.Flags(), "changes")
+		f.apply.Add(cmd.Flags(), "changes")
+		cmd.MarkFlagsMutuallyExclusive("dry-run", "apply")
+
 	case Report:
 		cmd = cobra.Command{
 			Use:   "report",
 			Short: "Report objects",
-			Long:  "Report is for computers.",
+			Long: "Report is for computers.\n\n" +
+				"Objects are rendered through a Go text/template given by --template, either a\n" +
+				"file or, for zones, one of the built-in templates: " + strings.Join(report.Builtins("zone"), ", ") + ".\n" +
+				"A built-in is used over a file of the same name, so give ./hosts for the file.",
 		}
 
+		cmd.AddCommand(
+			host.New(verb),
+			network.New(verb),
+			zone.New(verb))
+
 	case Remove:
 		cmd = cobra.Command{
 			Use:     "remove",
 			Aliases: []string{"del", "rm"},
 			Short:   "Remove objects",
+			Long: "Remove deletes the objects matching a glob. The matches are shown first and\n" +
+				"removed after confirming, or straight away with --yes. With --dry-run they are\n" +
+				"only shown. Removing every zone, make or global attr, by * or any glob that\n" +
+				"matches them all, also needs --all.",
 		}
 
+		rf.yes.Add(cmd.PersistentFlags(), "matches")
+		rf.dryRun.Add(cmd.PersistentFlags(), "matches")
+		rf.all.Add(cmd.PersistentFlags(), "zones, makes or global attrs")
+
 		cmd.AddCommand(
+			attr.New(verb),
 			appliance.New(verb),
 			cluster.New(verb),
 			environment.New(verb),
+			host.New(verb),
+			vendor.New(verb),
 			model.New(verb),
+			network.New(verb),
 			rack.New(verb),
 			zone.New(verb))
+
+	case UI:
+		cmd = cobra.Command{
+			Use:   "ui",
+			Short: "Browse and edit objects in a terminal UI",
+			Long: "UI is a full screen browser of the zones, makes and global attrs. Enter opens\n" +
+				"an object to its children, zone to clusters, appliances, environments, racks,\n" +
+				"networks and hosts, and a cluster, appliance, environment or rack to its hosts.\n" +
+				"The fields and attrs of the selected object are shown beside it, tab moves to\n" +
+				"the attrs and / searches the list as you type.\n\n" +
+				"a adds, r renames and d removes the selected object or attr, and e edits an\n" +
+				"attr's value, each after confirming, and ctrl-r reads the list again. The\n" +
+				"schema is backed up before each change, so stack undo takes back the last.",
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.ui()
+			},
+		}
+
+	case Undo:
+		var f confirmFlags
+
+		cmd = cobra.Command{
+			Use:   "undo",
+			Short: "Undo the last change",
+			Long: "Undo restores the newest backup that differs from the server, the schema from\n" +
+				"before the last change. The changes are shown first and made after confirming,\n" +
+				"or straight away with --yes. With --dry-run they are only shown.\n\n" +
+				"Undo is backed up like any other change, so undoing twice redoes. Fields set\n" +
+				"since the backup was taken are cleared, as with apply --prune.",
+			Args: cobra.NoArgs,
+			RunE: func(_ *cobra.Command, _ []string) error {
+				return r.undo(&f)
+			},
+		}
+
+		f.yes.Add(cmd.Flags(), "changes")
+		f.dryRun.Add(cmd.Flags(), "changes")
+	}
+
+	// The verbs that change the server, backup for restore.
+	switch verb {
+	case Add, Apply, Backup, Load, Remove, Set, UI, Undo:
+		r.forgetNamesAfter(&cmd)
 	}
 
 	return &cmd
 }
 
-func (r *Root) dump() error {
+func (r *Root) dump(f *dumpFlags) error {
 	var req pb.ReadSchemaRequest
 
-	if r.zoneFlag.Val() != "" {
-		req.SetZone(r.zoneFlag.Val())
+	if f.zone.Val() != "" {
+		req.SetZone(f.zone.Val())
 	}
-	if r.clusterFlag.Val() != "" {
-		req.SetCluster(r.clusterFlag.Val())
+	if f.cluster.Val() != "" {
+		req.SetCluster(f.cluster.Val())
 	}
-	if r.hostFlag.Val() != "" {
-		req.SetHost(r.hostFlag.Val())
+	if f.host.Val() != "" {
+		req.SetHost(f.host.Val())
 	}
 
 	resp, err := r.Client.Metal.ReadSchema(r.Client.Context(), &req)
@@ -155,21 +476,8 @@ func (r *Root) dump() error {
 
 	doc := resp.GetSchema()
 
-	if !r.jsonFlag.Val() { // parse json as yaml and re-marshal
-		var obj map[string]interface{}
-
-		b, err := protojson.MarshalOptions{
-			UseProtoNames: true,
-		}.Marshal(doc)
-		if err != nil {
-			return err
-		}
-
-		if err := yaml.Unmarshal(b, &obj); err != nil {
-			return err
-		}
-
-		return yaml.NewEncoder(os.Stdout).Encode(obj)
+	if !f.json.Val() {
+		return dumpYAML(doc, f.update.Val())
 	}
 
 	b, err := protojson.MarshalOptions{
@@ -185,47 +493,85 @@ func (r *Root) dump() error {
 	return nil
 }
 
-func (r *Root) load(filename string) error {
-	fin, err := os.Open(filename)
+// dumpYAML writes the schema as YAML to stdout or the --update file. Comments
+// in the file are kept on the objects they describe.
+func dumpYAML(doc *pb.Schema, file string) error {
+	var comments protoyaml.Comments
+
+	if file != "" {
+		b, err := os.ReadFile(file)
+
+		switch {
+		case err == nil:
+			if comments, err = protoyaml.ReadComments(b); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+
+	b, err := protoyaml.Marshal(doc, comments)
 	if err != nil {
 		return err
 	}
-	defer fin.Close()
 
-	data, err := io.ReadAll(fin)
+	if file != "" {
+		return os.WriteFile(file, b, 0o644)
+	}
+
+	_, err = os.Stdout.Write(b)
+
+	return err
+}
+
+func (r *Root) load(names []string, f *loadFlags) error {
+	doc, err := readSchema(names, f.format.Val())
 	if err != nil {
 		return err
 	}
 
-	var doc pb.Schema
+	resp, err := r.Client.Metal.ReadSchema(r.Client.Context(), &pb.ReadSchemaRequest{})
+	if err != nil {
+		return err
+	}
 
-	switch filepath.Ext(filename) {
-	case ".json":
-		if err := protojson.Unmarshal(data, &doc); err != nil {
-			return err
-		}
-	case ".yaml", ".yml":
-		var jsonMap map[string]interface{}
+	changes, err := schemaDiff(resp.GetSchema(), doc, false)
+	if err != nil {
+		return err
+	}
 
-		if err := yaml.Unmarshal(data, &jsonMap); err != nil {
-			return err
-		}
+	if len(changes) == 0 {
+		fmt.Println("No changes.")
+
+		return nil
+	}
+
+	if err := diff.Print(os.Stdout, changes, useColor(os.Stdout)); err != nil {
+		return err
+	}
 
-		jsonData, err := json.Marshal(jsonMap)
+	if f.dryRun.Val() {
+		return nil
+	}
+
+	if !f.apply.Val() {
+		ok, err := confirm("Apply these changes?")
 		if err != nil {
-			return err
+			return fmt.Errorf("%w, use --apply to load without confirming", err)
 		}
 
-		if err := protojson.Unmarshal(jsonData, &doc); err != nil {
-			return err
+		if !ok {
+			return errors.New("load cancelled")
 		}
+	}
 
-	default:
-		return errors.New("unknown file type")
+	if err := r.backupBefore("load"); err != nil {
+		return err
 	}
 
 	req := pb.CreateSchemaRequest_builder{
-		Schema: &doc,
+		Schema: doc,
 	}.Build()
 
 	_, err = r.Client.Metal.CreateSchema(r.Client.Context(), req)
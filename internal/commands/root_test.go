@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestAttrCommands walks the command tree of every verb and checks each attr
+// command is reachable under the verbs that support attrs, and only those.
+func TestAttrCommands(t *testing.T) {
+	attrVerbs := []Verb{Add, Set, List, Remove}
+
+	paths := [][]string{
+		{attribute},
+		{zone, attribute},
+		{cluster, attribute},
+		{rack, attribute},
+		{appliance, attribute},
+		{environment, attribute},
+		{model, attribute},
+	}
+
+	for _, verb := range VerbValues() {
+		t.Run(verb.String(), func(t *testing.T) {
+			var r Root
+
+			cmd := r.New(verb)
+			if cmd.Name() != verb.String() {
+				t.Fatalf("New(%s) is named %q", verb, cmd.Name())
+			}
+
+			want := slices.Contains(attrVerbs, verb)
+
+			for _, path := range paths {
+				found, rest, err := cmd.Find(path)
+				got := err == nil && len(rest) == 0 && found.Name() == attribute
+
+				if got && len(path) > 1 && found.Parent().Name() != path[0] {
+					t.Errorf("%s %v found under %s", verb, path, found.Parent().CommandPath())
+				}
+
+				if got != want {
+					t.Errorf("%s %v reachable = %v, want %v", verb, path, got, want)
+				}
+			}
+		})
+	}
+}
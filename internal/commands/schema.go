@@ -0,0 +1,544 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"endobit.io/metal-cli/internal/diff"
+	"endobit.io/metal-cli/internal/lint"
+	"endobit.io/metal-cli/internal/protoyaml"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// include is the directive that pulls other files into a schema, see
+// lint.Include.
+const include = lint.Include
+
+// readSchema reads and merges schema fragments from files, directories and
+// "-" for stdin, rejecting unknown fields. Directories are read recursively
+// for .json, .yaml and .yml files, leaving out those included by another, see
+// rootFiles. The format, json or yaml, is taken from
+// the file extension, and format is only for stdin and files without one.
+func readSchema(names []string, format string) (*pb.Schema, error) {
+	var tree any = map[string]any{}
+
+	files, err := rootFiles(names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		t, err := readSchemaTree(file, format, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if tree, err = merge(tree, t, ""); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc pb.Schema
+
+	if err := protoyaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func schemaFiles(name string) ([]string, error) {
+	if name == "-" {
+		return []string{name}, nil
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+
+	var files []string
+
+	err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && schemaFormat(path) != "" {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no schema files", name)
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// rootFiles returns the schema files of names, see schemaFiles, without the
+// ones another of them includes. Those are read through the include, in the
+// object holding it, so a directory can hold both a file and the fragments
+// it includes. Files that only include each other are kept, so the cycle is
+// reported when they are read.
+func rootFiles(names []string) ([]string, error) {
+	var files []string
+
+	for _, name := range names {
+		f, err := schemaFiles(name)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, f...)
+	}
+
+	includes := make(map[string][]string) // absolute paths of the files each includes
+
+	var reach func(file string, reached map[string]bool)
+
+	reach = func(file string, reached map[string]bool) {
+		paths, ok := includes[file]
+		if !ok {
+			paths = includedFiles(file)
+			includes[file] = paths
+		}
+
+		for _, p := range paths {
+			if !reached[p] {
+				reached[p] = true
+				reach(p, reached)
+			}
+		}
+	}
+
+	abs := make([]string, len(files))
+	included := make(map[string]bool)
+
+	for i, file := range files {
+		if file == "-" {
+			continue
+		}
+
+		p, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		abs[i] = p
+		reach(p, included)
+	}
+
+	var roots []string
+
+	reached := make(map[string]bool)
+
+	for i, file := range files {
+		if file == "-" {
+			roots = append(roots, file)
+		} else if !included[abs[i]] {
+			roots = append(roots, file)
+			reached[abs[i]] = true
+			reach(abs[i], reached)
+		}
+	}
+
+	for i, file := range files {
+		if file != "-" && !reached[abs[i]] {
+			roots = append(roots, file)
+			reached[abs[i]] = true
+			reach(abs[i], reached)
+		}
+	}
+
+	return roots, nil
+}
+
+// includedFiles returns the absolute paths of the files a schema file
+// includes directly. A file that cannot be read includes nothing here, its
+// error is reported when it is read.
+func includedFiles(file string) []string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var tree any
+
+	if yaml.Unmarshal(data, &tree) != nil { // YAML covers JSON
+		return nil
+	}
+
+	var (
+		files []string
+		walk  func(tree any)
+	)
+
+	walk = func(tree any) {
+		switch v := tree.(type) {
+		case map[string]any:
+			paths, _ := includePaths(v[include])
+
+			for _, p := range paths {
+				if !filepath.IsAbs(p) {
+					p = filepath.Join(filepath.Dir(file), p)
+				}
+
+				matches, _ := filepath.Glob(p)
+
+				for _, m := range matches {
+					if m, err := filepath.Abs(m); err == nil {
+						files = append(files, m)
+					}
+				}
+			}
+
+			for k, e := range v {
+				if k != include {
+					walk(e)
+				}
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+
+	walk(tree)
+
+	return files
+}
+
+func schemaFormat(name string) string {
+	switch filepath.Ext(name) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+
+	return ""
+}
+
+// readSchemaTree decodes a file and its includes, in format if the file has
+// no extension saying otherwise. Seen holds the files being read, to catch
+// include cycles.
+func readSchemaTree(name, format string, seen []string) (any, error) {
+	if f := schemaFormat(name); f != "" {
+		format = f
+	}
+
+	if format == "" {
+		return nil, fmt.Errorf("%s: unknown file type, use --format", name)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if name == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tree any
+
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, &tree)
+	case "yaml":
+		err = yaml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	dir := "."
+	if name != "-" {
+		dir = filepath.Dir(name)
+
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.Contains(seen, abs) {
+			return nil, fmt.Errorf("%s: include cycle", name)
+		}
+
+		seen = append(seen, abs)
+	}
+
+	return resolveIncludes(tree, dir, seen)
+}
+
+// resolveIncludes replaces the include directives in a tree with the
+// contents of the files they name.
+func resolveIncludes(tree any, dir string, seen []string) (any, error) {
+	switch v := tree.(type) {
+	case map[string]any:
+		paths, err := includePaths(v[include])
+		if err != nil {
+			return nil, err
+		}
+
+		delete(v, include)
+
+		for k, e := range v {
+			if v[k], err = resolveIncludes(e, dir, seen); err != nil {
+				return nil, err
+			}
+		}
+
+		var out any = v
+
+		for _, p := range paths {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+
+			matches, err := filepath.Glob(p)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s: no files match %s", include, p)
+			}
+
+			for _, m := range matches {
+				t, err := readSchemaTree(m, "", seen)
+				if err != nil {
+					return nil, err
+				}
+
+				if out, err = merge(out, t, ""); err != nil {
+					return nil, fmt.Errorf("%s: %w", m, err)
+				}
+			}
+		}
+
+		return out, nil
+
+	case []any:
+		for i, e := range v {
+			var err error
+
+			if v[i], err = resolveIncludes(e, dir, seen); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+func includePaths(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		paths := make([]string, len(v))
+
+		for i, p := range v {
+			s, ok := p.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected paths", include)
+			}
+
+			paths[i] = s
+		}
+
+		return paths, nil
+	}
+
+	return nil, fmt.Errorf("%s: expected a path or list of paths", include)
+}
+
+// merge merges two schema trees. Objects are merged key by key and lists of
+// named objects by name, so fragments can each add to the same zone. Any
+// other values must agree.
+func merge(dst, src any, path string) (any, error) {
+	if dst == nil {
+		return src, nil
+	}
+
+	if src == nil {
+		return dst, nil
+	}
+
+	if d, ok := dst.(map[string]any); ok {
+		if s, ok := src.(map[string]any); ok {
+			for k, v := range s {
+				m, err := merge(d[k], v, strings.TrimPrefix(path+"."+k, "."))
+				if err != nil {
+					return nil, err
+				}
+
+				d[k] = m
+			}
+
+			return d, nil
+		}
+	}
+
+	if d, ok := dst.([]any); ok {
+		if s, ok := src.([]any); ok && namedList(d) && namedList(s) {
+			for _, e := range s {
+				name := e.(map[string]any)["name"]
+
+				i := slices.IndexFunc(d, func(o any) bool {
+					return o.(map[string]any)["name"] == name
+				})
+				if i < 0 {
+					d = append(d, e)
+
+					continue
+				}
+
+				m, err := merge(d[i], e, fmt.Sprintf("%s[%v]", path, name))
+				if err != nil {
+					return nil, err
+				}
+
+				d[i] = m
+			}
+
+			return d, nil
+		}
+	}
+
+	if !sameValue(dst, src) {
+		return nil, fmt.Errorf("conflicting values for %s: %s and %s", path, diff.Text(dst), diff.Text(src))
+	}
+
+	return dst, nil
+}
+
+// sameValue reports whether two values of a schema tree are equal, taking
+// numbers of any type as equal by value, as YAML decodes 10 as a uint64 and
+// JSON as a float64.
+func sameValue(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+
+		return ok && maps.EqualFunc(a, b, sameValue)
+
+	case []any:
+		b, ok := b.([]any)
+
+		return ok && slices.EqualFunc(a, b, sameValue)
+	}
+
+	x, ok := number(a)
+	if !ok {
+		return reflect.DeepEqual(a, b)
+	}
+
+	y, ok := number(b)
+
+	return ok && x == y
+}
+
+// number returns a decoded number as a float64.
+func number(v any) (float64, bool) {
+	r := reflect.ValueOf(v)
+
+	switch {
+	case r.CanInt():
+		return float64(r.Int()), true
+	case r.CanUint():
+		return float64(r.Uint()), true
+	case r.CanFloat():
+		return r.Float(), true
+	}
+
+	return 0, false
+}
+
+func namedList(l []any) bool {
+	for _, e := range l {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// schemaDiff compares two schemas by their JSON form, see diff.Compare.
+func schemaDiff(from, to *pb.Schema, prune bool) ([]diff.Change, error) {
+	a, err := schemaTree(from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := schemaTree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff.Compare(a, b, prune), nil
+}
+
+func schemaTree(doc *pb.Schema) (any, error) {
+	b, err := protojson.MarshalOptions{
+		UseProtoNames: true,
+	}.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree any
+
+	if err := json.Unmarshal(b, &tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestReadSchemaTree reads a directory with --format json, which only applies
+// to the file without an extension, and merges a number given in YAML with the
+// same number given in JSON.
+func TestReadSchemaTree(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a.json": `{"zones": [{"name": "east", "mtu": 9000}]}`,
+		"b.yaml": "zones:\n- name: east\n  mtu: 9000\n",
+		"c":      `{"zones": [{"name": "west"}]}`,
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tree any = map[string]any{}
+
+	for _, name := range []string{"a.json", "b.yaml", "c"} {
+		sub, err := readSchemaTree(filepath.Join(dir, name), "json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tree, err = merge(tree, sub, ""); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+
+	zones := tree.(map[string]any)["zones"].([]any)
+	if len(zones) != 2 {
+		t.Errorf("got zones %v, want east and west", zones)
+	}
+
+	conflict := map[string]any{"zones": []any{map[string]any{"name": "east", "mtu": 1500.0}}}
+	if _, err := merge(tree, conflict, ""); err == nil {
+		t.Error("merged a different mtu")
+	}
+}
+
+// TestRootFiles reads a directory holding a file and the fragments it
+// includes, which are read once, through the include, and not as schemas of
+// their own.
+func TestRootFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"site.yaml":     "zones:\n- name: east\n  racks:\n  - $include: racks/*.yaml\n",
+		"west.json":     `{"zones": [{"name": "west"}]}`,
+		"racks/r1.yaml": "name: r1\n",
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := rootFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(dir, "site.yaml"), filepath.Join(dir, "west.json")}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	var tree any = map[string]any{}
+
+	for _, file := range got {
+		sub, err := readSchemaTree(file, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tree, err = merge(tree, sub, ""); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	zones := tree.(map[string]any)["zones"].([]any)
+	racks, _ := zones[0].(map[string]any)["racks"].([]any)
+
+	if len(zones) != 2 || len(racks) != 1 || racks[0].(map[string]any)["name"] != "r1" {
+		t.Errorf("got zones %v, want east with rack r1 and west", zones)
+	}
+}
+
+// TestRootFilesCycle checks files that only include each other are still
+// read, so the cycle is reported rather than nothing loaded.
+func TestRootFilesCycle(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, data := range map[string]string{
+		"a.yaml": "$include: b.yaml\n",
+		"b.yaml": "$include: a.yaml\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := rootFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("got %q, want one of the files", got)
+	}
+
+	if _, err := readSchemaTree(got[0], "", nil); err == nil {
+		t.Error("no include cycle error")
+	}
+}
@@ -0,0 +1,931 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"endobit.io/metal-cli/internal/tui"
+	pb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// browser is the state of stack ui: a stack of levels, each a list of
+// objects, with the attrs of the selected object beside them.
+type browser struct {
+	r      *Root
+	screen *tui.Screen
+	kinds  map[string]*kind // by schema path, like zones.racks
+	levels []*level
+	attrs  bool       // the attrs have the focus
+	search *tui.Input // while searching
+	status string
+	failed bool // the status is an error
+}
+
+// level is a list of objects of one kind, or of groups of objects.
+type level struct {
+	title  string            // in the breadcrumbs
+	object string            // like rack, or "" for groups
+	kind   *kind             // how to add, rename and remove the objects
+	scope  map[string]string // request fields naming the parents
+	set    map[string]any    // fields of new objects, like a host's cluster
+	values bool              // the objects are attrs, with values
+	load   func() ([]*item, error)
+
+	items  []*item
+	filter string
+	list   tui.List
+}
+
+// item is an object, an attr or a group in a level.
+type item struct {
+	name   string
+	fields [][2]string
+	value  *string       // for attrs
+	open   func() *level // the children, nil for leaves
+	attrs  func() *level // nil for objects without attrs
+	loaded *level        // the attrs once read
+}
+
+func (r *Root) ui() error {
+	screen, err := tui.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	defer screen.Close()
+
+	b := browser{
+		r:      r,
+		screen: screen,
+		kinds:  make(map[string]*kind),
+	}
+
+	kinds := r.kinds()
+	for i := range kinds {
+		b.kinds[kinds[i].path] = &kinds[i]
+	}
+
+	b.push(b.home())
+
+	return b.run()
+}
+
+func (b *browser) ctx() context.Context {
+	return b.r.Client.Context()
+}
+
+func (b *browser) run() error {
+	for {
+		b.draw()
+
+		if err := b.screen.Flush(); err != nil {
+			return err
+		}
+
+		k, err := b.screen.ReadKey()
+		if err != nil {
+			return err
+		}
+
+		if b.search != nil {
+			b.searchKey(k)
+
+			continue
+		}
+
+		b.status = ""
+
+		if quit := b.key(k); quit {
+			return nil
+		}
+	}
+}
+
+// key handles a key outside of search, reporting whether to quit.
+func (b *browser) key(k tui.Key) bool {
+	l := b.focused()
+	_, height := b.screen.Size()
+
+	if l != nil && l.list.Handle(k, len(l.visible()), height-4) {
+		return false
+	}
+
+	switch k.Code {
+	case tui.Enter, tui.Right:
+		if it := b.current(); it != nil && !b.attrs && it.open != nil {
+			b.push(it.open())
+		}
+
+	case tui.Left, tui.Backspace, tui.Esc:
+		switch {
+		case b.attrs:
+			b.attrs = false
+		case b.top().filter != "":
+			b.top().filter = ""
+		case len(b.levels) > 1:
+			b.levels = b.levels[:len(b.levels)-1]
+		}
+
+	case tui.Tab:
+		if it := b.selected(); it != nil && it.attrs != nil {
+			b.attrs = !b.attrs
+		}
+
+	case tui.Ctrl:
+		switch k.Rune {
+		case 'c':
+			return true
+		case 'r':
+			b.reload(b.top())
+		}
+
+	case tui.Rune:
+		switch k.Rune {
+		case 'q':
+			return true
+		case 'j':
+			return b.key(tui.Key{Code: tui.Down})
+		case 'k':
+			return b.key(tui.Key{Code: tui.Up})
+		case '/':
+			if l != nil {
+				b.search = tui.NewInput(l.filter)
+			}
+		case 'a':
+			b.add(l)
+		case 'r':
+			b.rename(l)
+		case 'd':
+			b.remove(l)
+		case 'e':
+			b.edit(l)
+		}
+	}
+
+	return false
+}
+
+// searchKey narrows the focused list as the search is typed. Enter keeps
+// the filter, Esc drops it.
+func (b *browser) searchKey(k tui.Key) {
+	l := b.focused()
+
+	switch {
+	case k.Code == tui.Enter:
+		b.search = nil
+	case k.Code == tui.Esc, k.Code == tui.Ctrl && k.Rune == 'c':
+		b.search = nil
+		l.filter = ""
+	case b.search.Handle(k):
+		l.filter = b.search.String()
+		l.list.Cursor = 0
+	}
+}
+
+func (b *browser) push(l *level) {
+	b.reload(l)
+	b.levels = append(b.levels, l)
+	b.attrs = false
+}
+
+// reload reads a level's items again, keeping the cursor on the same name
+// if it is still there.
+func (b *browser) reload(l *level) {
+	var name string
+
+	if vis := l.visible(); l.list.Cursor < len(vis) {
+		name = vis[l.list.Cursor].name
+	}
+
+	items, err := l.load()
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	l.items = items
+
+	if i := slices.IndexFunc(l.visible(), func(it *item) bool { return it.name == name }); i >= 0 {
+		l.list.Cursor = i
+	}
+}
+
+func (b *browser) fail(err error) {
+	b.status = err.Error()
+	b.failed = true
+}
+
+func (b *browser) done(msg string) {
+	b.status = msg
+	b.failed = false
+}
+
+// top returns the level shown.
+func (b *browser) top() *level {
+	return b.levels[len(b.levels)-1]
+}
+
+// selected returns the item under the cursor of the level shown.
+func (b *browser) selected() *item {
+	return b.top().selected()
+}
+
+// focused returns the list with the focus, the level shown or the attrs of
+// its selected item.
+func (b *browser) focused() *level {
+	if !b.attrs {
+		return b.top()
+	}
+
+	return b.attrLevel(b.selected())
+}
+
+// current returns the item under the cursor of the focused list.
+func (b *browser) current() *item {
+	if l := b.focused(); l != nil {
+		return l.selected()
+	}
+
+	return nil
+}
+
+// attrLevel returns the attrs of an item, reading them the first time.
+func (b *browser) attrLevel(it *item) *level {
+	if it == nil || it.attrs == nil {
+		return nil
+	}
+
+	if it.loaded == nil {
+		it.loaded = it.attrs()
+		b.reload(it.loaded)
+	}
+
+	return it.loaded
+}
+
+// visible returns the items matching the filter, ignoring case.
+func (l *level) visible() []*item {
+	if l.filter == "" {
+		return l.items
+	}
+
+	filter := strings.ToLower(l.filter)
+
+	var items []*item
+
+	for _, it := range l.items {
+		if strings.Contains(strings.ToLower(it.name), filter) {
+			items = append(items, it)
+		}
+	}
+
+	return items
+}
+
+func (l *level) selected() *item {
+	vis := l.visible()
+	if l.list.Cursor < len(vis) {
+		return vis[l.list.Cursor]
+	}
+
+	return nil
+}
+
+// request returns the request fields naming an object of the level.
+func (l *level) request(key, name string) map[string]any {
+	req := map[string]any{key: name}
+	for k, v := range l.scope {
+		req[k] = v
+	}
+
+	return req
+}
+
+// where describes the parents of a level's objects, like " in zone east".
+func (l *level) where() string {
+	var s string
+
+	for _, k := range slices.Sorted(maps.Keys(l.scope)) {
+		s += fmt.Sprintf(" in %s %s", k, l.scope[k])
+	}
+
+	return s
+}
+
+// canEdit reports whether the level's objects can be changed, reporting
+// why not if they cannot.
+func (b *browser) canEdit(l *level) bool {
+	if l == nil || l.kind == nil {
+		b.fail(errors.New("nothing to change here"))
+
+		return false
+	}
+
+	return true
+}
+
+func (b *browser) add(l *level) {
+	if !b.canEdit(l) {
+		return
+	}
+
+	name, ok, err := b.screen.Prompt(b.draw, "New "+l.object+l.where(), "")
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	if !ok || name == "" {
+		return
+	}
+
+	fields := maps.Clone(l.set)
+	question := fmt.Sprintf("Add %s %s%s?", l.object, name, l.where())
+
+	if l.values {
+		value, ok, err := b.screen.Prompt(b.draw, "Value of "+name, "")
+		if err != nil {
+			b.fail(err)
+
+			return
+		}
+
+		if !ok {
+			return
+		}
+
+		fields = map[string]any{"value": value}
+		question = fmt.Sprintf("Add %s %s = %q%s?", l.object, name, value, l.where())
+	}
+
+	b.change(l, question, func() error {
+		req := l.request("name", name)
+		if err := l.kind.create(b.ctx(), req); err != nil {
+			return err
+		}
+
+		if len(fields) == 0 {
+			return nil
+		}
+
+		req["fields"] = fields
+
+		if err := l.kind.update(b.ctx(), req); err != nil {
+			return fmt.Errorf("added %s but could not set %s: %w",
+				name, strings.Join(slices.Sorted(maps.Keys(fields)), ", "), err)
+		}
+
+		return nil
+	}, "Added "+name+".")
+}
+
+func (b *browser) rename(l *level) {
+	it := b.current()
+	if !b.canEdit(l) || it == nil {
+		return
+	}
+
+	name, ok, err := b.screen.Prompt(b.draw, "Rename "+l.object+" "+it.name, it.name)
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	if !ok || name == "" || name == it.name {
+		return
+	}
+
+	b.change(l, fmt.Sprintf("Rename %s %s%s to %s?", l.object, it.name, l.where(), name), func() error {
+		req := l.request("name", it.name)
+		req["fields"] = map[string]any{"name": name}
+
+		return l.kind.update(b.ctx(), req)
+	}, "Renamed "+it.name+" to "+name+".")
+}
+
+func (b *browser) remove(l *level) {
+	it := b.current()
+	if !b.canEdit(l) || it == nil {
+		return
+	}
+
+	// Deletes take a glob, which must only match this object.
+	glob, err := globQuote(it.name)
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	question := fmt.Sprintf("Remove %s %s%s?", l.object, it.name, l.where())
+	if it.open != nil {
+		question += "\nEverything in it is removed too."
+	}
+
+	b.change(l, question, func() error {
+		return l.kind.remove(b.ctx(), l.request("glob", glob))
+	}, "Removed "+it.name+".")
+}
+
+// edit changes the value of an attr.
+func (b *browser) edit(l *level) {
+	it := b.current()
+	if !b.canEdit(l) || it == nil {
+		return
+	}
+
+	if !l.values {
+		b.fail(errors.New("only attr values can be edited, use r to rename"))
+
+		return
+	}
+
+	value, ok, err := b.screen.Prompt(b.draw, "Value of "+it.name, *it.value)
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	if !ok || value == *it.value {
+		return
+	}
+
+	b.change(l, fmt.Sprintf("Set %s %s%s to %q?", l.object, it.name, l.where(), value), func() error {
+		req := l.request("name", it.name)
+		req["fields"] = map[string]any{"value": value}
+
+		return l.kind.update(b.ctx(), req)
+	}, "Set "+it.name+".")
+}
+
+// change makes a change after confirming it and reads the level again.
+func (b *browser) change(l *level, question string, do func() error, msg string) {
+	ok, err := b.screen.Confirm(b.draw, question)
+	if err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	if !ok {
+		return
+	}
+
+	if err := b.r.backupBefore("ui"); err != nil {
+		b.fail(err)
+
+		return
+	}
+
+	if err := do(); err != nil {
+		b.fail(err)
+	} else {
+		b.done(msg)
+	}
+
+	// A change can fail part way, like an add that creates the object but
+	// cannot set its fields, so the level is read again either way.
+	b.reload(l)
+
+	// The attrs and children of a level's items may have changed with it.
+	for _, it := range l.items {
+		it.loaded = nil
+	}
+}
+
+// draw draws the breadcrumbs, the list of the level shown, the fields and
+// attrs of its selected item, and the status and help lines.
+func (b *browser) draw() {
+	s := b.screen
+	s.Clear()
+
+	width, height := s.Size()
+
+	titles := make([]string, len(b.levels))
+	for i, l := range b.levels {
+		titles[i] = l.title
+	}
+
+	s.Print(0, 0, width, tui.Bold, " "+strings.Join(titles, " › "))
+
+	l := b.top()
+	left := min(max(width/3, 24), width/2)
+	rows := height - 4
+
+	l.list.Draw(s, 1, 0, left, rows, names(l.visible()), !b.attrs)
+
+	if len(l.visible()) == 0 {
+		s.Print(1, 0, left, tui.Dim, " none")
+	}
+
+	for i := range rows {
+		s.Print(1+i, left, 1, tui.Dim, "│")
+	}
+
+	if it := l.selected(); it != nil {
+		b.drawItem(it, 1, left+2, width-left-2, rows)
+	}
+
+	switch {
+	case b.status == "":
+	case b.failed:
+		s.Print(height-2, 0, width, tui.Red, " "+b.status)
+	default:
+		s.Print(height-2, 0, width, tui.Cyan, " "+b.status)
+	}
+
+	if b.search != nil {
+		s.Print(height-1, 0, 2, tui.Plain, " /")
+		b.search.Draw(s, height-1, 2, width-2)
+
+		return
+	}
+
+	help := " enter open  ← back  / search  q quit"
+	if l.kind != nil || b.attrs {
+		help = " enter open  ← back  tab attrs  / search  a add  r rename  d remove  e edit  q quit"
+	}
+
+	s.Print(height-1, 0, width, tui.Dim, help)
+}
+
+// drawItem draws an item's fields and then its attrs.
+func (b *browser) drawItem(it *item, row, col, width, height int) {
+	s := b.screen
+	end := row + height
+
+	s.Print(row, col, width, tui.Bold, it.name)
+	row += 2
+
+	for _, f := range it.fields {
+		s.Print(row, col, width, tui.Plain, fmt.Sprintf("%-12s %s", f[0]+":", f[1]))
+		row++
+	}
+
+	al := b.attrLevel(it)
+	if al == nil {
+		return
+	}
+
+	if len(it.fields) > 0 {
+		row++
+	}
+
+	title := "attrs"
+	if al.filter != "" {
+		title += " matching " + al.filter
+	}
+
+	s.Print(row, col, width, tui.Cyan, title)
+	row++
+
+	if len(al.visible()) == 0 {
+		s.Print(row, col, width, tui.Dim, " none")
+
+		return
+	}
+
+	rows := make([]string, len(al.visible()))
+	for i, a := range al.visible() {
+		rows[i] = a.name + " = " + attrText(*a.value).String()
+	}
+
+	al.list.Draw(s, row, col, width, end-row, rows, b.attrs)
+}
+
+func names(items []*item) []string {
+	s := make([]string, len(items))
+	for i, it := range items {
+		s[i] = it.name
+		if it.open != nil {
+			s[i] += " ›"
+		}
+	}
+
+	return s
+}
+
+// home is the first level: the zones, makes and global attrs.
+func (b *browser) home() *level {
+	return &level{
+		title: "stack",
+		load: func() ([]*item, error) {
+			return []*item{
+				{name: "zones", open: b.zones},
+				{name: "makes", open: b.makes},
+				{name: "global attrs", open: func() *level {
+					return b.attrLevelOf("global "+attribute, "attrs", nil, func() ([]*item, error) {
+						return attrItems(b.r.Client.NewGlobalAttrReader("").Responses())
+					})
+				}},
+			}, nil
+		},
+	}
+}
+
+func (b *browser) zones() *level {
+	return &level{
+		title:  "zones",
+		object: zone,
+		kind:   b.kinds["zones"],
+		load: func() ([]*item, error) {
+			return objectItems(b.r.Client.NewZoneReader("").Responses(), nil, func(resp *pb.ReadZonesResponse, it *item) {
+				z := resp.GetName()
+				scope := map[string]string{"zone": z}
+
+				it.open = func() *level { return b.zone(z) }
+				it.attrs = func() *level {
+					return b.attrLevelOf(zone+" "+attribute, "zones.attrs", scope, func() ([]*item, error) {
+						return attrItems(b.r.Client.NewZoneAttrReader(z, "").Responses())
+					})
+				}
+			})
+		},
+	}
+}
+
+// zone lists the kinds of objects in a zone.
+func (b *browser) zone(z string) *level {
+	c := b.r.Client
+	scope := map[string]string{"zone": z}
+
+	return &level{
+		title: zone + " " + z,
+		load: func() ([]*item, error) {
+			return []*item{
+				{name: "clusters", open: func() *level {
+					return b.parents(cluster, "zones.clusters", scope, func() ([]*item, error) {
+						return objectItems(c.NewClusterReader(z, "").Responses(), nil, func(resp *pb.ReadClustersResponse, it *item) {
+							b.hostParent(it, z, cluster, resp.GetName(), func() ([]*item, error) {
+								return attrItems(c.NewClusterAttrReader(z, resp.GetName(), "").Responses())
+							})
+						})
+					})
+				}},
+				{name: "appliances", open: func() *level {
+					return b.parents(appliance, "zones.appliances", scope, func() ([]*item, error) {
+						return objectItems(c.NewApplianceReader(z, "").Responses(), nil, func(resp *pb.ReadAppliancesResponse, it *item) {
+							b.hostParent(it, z, appliance, resp.GetName(), func() ([]*item, error) {
+								return attrItems(c.NewApplianceAttrReader(z, resp.GetName(), "").Responses())
+							})
+						})
+					})
+				}},
+				{name: "environments", open: func() *level {
+					return b.parents(environment, "zones.environments", scope, func() ([]*item, error) {
+						return objectItems(c.NewEnvironmentReader(z, "").Responses(), nil, func(resp *pb.ReadEnvironmentsResponse, it *item) {
+							b.hostParent(it, z, environment, resp.GetName(), func() ([]*item, error) {
+								return attrItems(c.NewEnvironmentAttrReader(z, resp.GetName(), "").Responses())
+							})
+						})
+					})
+				}},
+				{name: "racks", open: func() *level {
+					return b.parents(rack, "zones.racks", scope, func() ([]*item, error) {
+						return objectItems(c.NewRackReader(z, "").Responses(), nil, func(resp *pb.ReadRacksResponse, it *item) {
+							b.hostParent(it, z, rack, resp.GetName(), func() ([]*item, error) {
+								return attrItems(c.NewRackAttrReader(z, resp.GetName(), "").Responses())
+							})
+						})
+					})
+				}},
+				{name: "networks", open: func() *level {
+					return b.parents(network, "zones.networks", scope, func() ([]*item, error) {
+						return objectItems(c.NewNetworkReader(z, "").Responses(), nil, nil)
+					})
+				}},
+				{name: "hosts", open: func() *level {
+					return b.hosts(z, "", "")
+				}},
+			}, nil
+		},
+	}
+}
+
+// parents returns a level of objects of a kind in a zone.
+func (b *browser) parents(object, path string, scope map[string]string, load func() ([]*item, error)) *level {
+	return &level{
+		title:  object + "s",
+		object: object,
+		kind:   b.kinds[path],
+		scope:  scope,
+		load:   load,
+	}
+}
+
+// hostParent makes an item of a cluster, appliance, environment or rack
+// open to its hosts and show its attrs.
+func (b *browser) hostParent(it *item, z, object, name string, attrs func() ([]*item, error)) {
+	it.open = func() *level {
+		return b.hosts(z, object, name)
+	}
+
+	it.attrs = func() *level {
+		scope := map[string]string{"zone": z, object: name}
+
+		return b.attrLevelOf(object+" "+attribute, "zones."+object+"s.attrs", scope, attrs)
+	}
+}
+
+// hosts lists the hosts of a zone, or only those with a cluster, appliance,
+// environment or rack. New hosts get that field.
+func (b *browser) hosts(z, field, value string) *level {
+	l := level{
+		title:  "hosts",
+		object: host,
+		kind:   b.kinds["zones.hosts"],
+		scope:  map[string]string{"zone": z},
+		load: func() ([]*item, error) {
+			keep := func(resp *pb.ReadHostsResponse) bool {
+				switch field {
+				case cluster:
+					return resp.GetCluster() == value
+				case appliance:
+					return resp.GetAppliance() == value
+				case environment:
+					return resp.GetEnvironment() == value
+				case rack:
+					return resp.GetRack() == value
+				}
+
+				return true
+			}
+
+			return objectItems(b.r.Client.NewHostReader(z, "").Responses(), keep, func(resp *pb.ReadHostsResponse, it *item) {
+				h := resp.GetName()
+
+				it.open = func() *level {
+					return &level{
+						title:  host + " " + h,
+						object: iface,
+						kind:   b.kinds["zones.hosts.interfaces"],
+						scope:  map[string]string{"zone": z, "host": h},
+						load: func() ([]*item, error) {
+							return objectItems(b.r.Client.NewHostInterfaceReader(z, h, "").Responses(), nil, nil)
+						},
+					}
+				}
+			})
+		},
+	}
+
+	if field != "" {
+		l.title = fmt.Sprintf("%s %s hosts", field, value)
+		l.set = map[string]any{field: value}
+	}
+
+	return &l
+}
+
+func (b *browser) makes() *level {
+	c := b.r.Client
+
+	return &level{
+		title:  "makes",
+		object: vendor,
+		kind:   b.kinds["makes"],
+		load: func() ([]*item, error) {
+			return objectItems(c.NewMakeReader("").Responses(), nil, func(resp *pb.ReadMakesResponse, it *item) {
+				m := resp.GetName()
+
+				it.open = func() *level {
+					return &level{
+						title:  vendor + " " + m,
+						object: model,
+						kind:   b.kinds["makes.models"],
+						scope:  map[string]string{"make": m},
+						load: func() ([]*item, error) {
+							return objectItems(c.NewModelReader(m, "").Responses(), nil, func(resp *pb.ReadModelsResponse, it *item) {
+								name := resp.GetName()
+
+								it.attrs = func() *level {
+									scope := map[string]string{"model": name}
+
+									return b.attrLevelOf(model+" "+attribute, "makes.models.attrs", scope, func() ([]*item, error) {
+										return attrItems(c.NewModelAttrReader(name, "").Responses())
+									})
+								}
+							})
+						},
+					}
+				}
+			})
+		},
+	}
+}
+
+// attrLevelOf returns a level of attrs.
+func (b *browser) attrLevelOf(object, path string, scope map[string]string, load func() ([]*item, error)) *level {
+	return &level{
+		title:  attribute + "s",
+		object: object,
+		kind:   b.kinds[path],
+		scope:  scope,
+		values: true,
+		load:   load,
+	}
+}
+
+// objectItems reads objects into items, showing their fields beside them.
+// Keep picks the objects, all of them if nil, and setup adds children and
+// attrs.
+func objectItems[T interface {
+	proto.Message
+	GetName() string
+}](seq iter.Seq2[T, error], keep func(T) bool, setup func(T, *item)) ([]*item, error) {
+	var items []*item
+
+	for resp, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		if keep != nil && !keep(resp) {
+			continue
+		}
+
+		fields, err := responseFields(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		it := item{name: resp.GetName(), fields: fields}
+		if setup != nil {
+			setup(resp, &it)
+		}
+
+		items = append(items, &it)
+	}
+
+	return items, nil
+}
+
+func attrItems[T attrResponse](seq iter.Seq2[T, error]) ([]*item, error) {
+	var items []*item
+
+	for resp, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		value := resp.GetValue()
+
+		items = append(items, &item{
+			name:   resp.GetName(),
+			fields: [][2]string{{"value", attrText(value).String()}},
+			value:  &value,
+		})
+	}
+
+	return items, nil
+}
+
+// responseFields returns the fields of a read response by their proto
+// names, other than its name and parents.
+func responseFields(m proto.Message) ([][2]string, error) {
+	b, err := protojson.MarshalOptions{
+		UseProtoNames: true,
+	}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	var out [][2]string
+
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		switch k {
+		case "name", "zone", "make", "host":
+			continue
+		}
+
+		v, ok := fields[k].(string)
+		if !ok {
+			b, _ := json.Marshal(fields[k])
+			v = string(b)
+		}
+
+		out = append(out, [2]string{k, v})
+	}
+
+	return out, nil
+}
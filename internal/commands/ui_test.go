@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func itemsNamed(names ...string) []*item {
+	items := make([]*item, len(names))
+	for i, name := range names {
+		items[i] = &item{name: name}
+	}
+
+	return items
+}
+
+func TestLevelVisible(t *testing.T) {
+	l := level{items: itemsNamed("Rack-01", "rack-02", "switch-01")}
+
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{"", []string{"Rack-01", "rack-02", "switch-01"}},
+		{"rack", []string{"Rack-01", "rack-02"}},
+		{"RACK-0", []string{"Rack-01", "rack-02"}},
+		{"01", []string{"Rack-01", "switch-01"}},
+		{"pdu", nil},
+	}
+
+	for _, tt := range tests {
+		l.filter = tt.filter
+
+		if got := names(l.visible()); !slices.Equal(got, tt.want) {
+			t.Errorf("filter %q: got %q, want %q", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// TestReload keeps the cursor on the selected item when items before it
+// come and go, and leaves it alone when the item is gone or the level cannot
+// be read.
+func TestReload(t *testing.T) {
+	var (
+		items []*item
+		err   error
+	)
+
+	l := level{
+		items: itemsNamed("a", "b", "c", "d"),
+		load: func() ([]*item, error) {
+			return items, err
+		},
+	}
+
+	l.list.Cursor = 2
+
+	var b browser
+
+	items = itemsNamed("a", "c", "d")
+	b.reload(&l)
+
+	if got := l.selected(); got == nil || got.name != "c" {
+		t.Errorf("got %v after removing b, want c", got)
+	}
+
+	items = itemsNamed("a", "aa", "b", "c")
+	b.reload(&l)
+
+	if got := l.selected(); got == nil || got.name != "c" {
+		t.Errorf("got %v after adding aa and b, want c", got)
+	}
+
+	items = itemsNamed("a", "b", "d", "e")
+	b.reload(&l)
+
+	if l.list.Cursor != 3 {
+		t.Errorf("got cursor %d after removing c, want it left at 3", l.list.Cursor)
+	}
+
+	items, err = nil, errors.New("unreachable")
+	b.reload(&l)
+
+	if !b.failed || len(l.items) != 4 {
+		t.Errorf("got status %q and %d items after failing to read, want an error and the items kept",
+			b.status, len(l.items))
+	}
+
+	l.filter = "d"
+	l.list.Cursor = 0
+	items, err = itemsNamed("c", "d", "dd"), nil
+	b.reload(&l)
+
+	if got := l.selected(); got == nil || got.name != "d" {
+		t.Errorf("got %v with filter d, want d", got)
+	}
+}
@@ -1,5 +1,46 @@
 package commands
 
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"endobit.io/metal"
+
+	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/output"
+)
+
+// listFlags are the persistent flags of list, read by the list command of
+// each object below it.
+type listFlags struct {
+	output   flags.Output
+	columns  flags.Columns
+	sortBy   flags.SortBy
+	reverse  flags.Reverse
+	limit    flags.Limit
+	where    flags.Where
+	selector flags.Selector
+}
+
+// removeFlags are the persistent flags of remove, read by the remove command
+// of each object below it.
+type removeFlags struct {
+	yes    flags.Yes
+	dryRun flags.DryRun
+	all    flags.All
+
+	// backup is called before the first delete, see Root.backupBefore.
+	backup func(command string) error
+}
+
 func Optional[T comparable](v T) *T {
 	var zero T
 
@@ -10,6 +51,249 @@ func Optional[T comparable](v T) *T {
 	return nil
 }
 
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var s []T
+
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, v)
+	}
+
+	return s, nil
+}
+
+// newWriter returns the writer for a list of objects in the --output format.
+func (f *listFlags) newWriter(client *metal.Client, object string) (*output.Writer, error) {
+	opts := output.Options{
+		SortBy:   f.sortBy.Val(),
+		Reverse:  f.reverse.Val(),
+		Limit:    int(f.limit.Val()),
+		Where:    f.where.Val(),
+		Selector: f.selector.Val(),
+		Attrs:    objectAttrs(client, object),
+	}
+
+	if s := f.columns.Val(); s != "" {
+		opts.Columns = strings.Split(s, ",")
+	}
+
+	return output.New(os.Stdout, f.output.Val(), object, opts)
+}
+
+// objectAttrs returns how to read the attrs of a listed object for attr
+// selectors, or nil for objects without attrs.
+func objectAttrs(c *metal.Client, object string) func(map[string]string) (map[string]string, error) {
+	switch object {
+	case zone:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewZoneAttrReader(row[zone], "").Responses())
+		}
+	case cluster:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewClusterAttrReader(row[zone], row[cluster], "").Responses())
+		}
+	case rack:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewRackAttrReader(row[zone], row[rack], "").Responses())
+		}
+	case appliance:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewApplianceAttrReader(row[zone], row[appliance], "").Responses())
+		}
+	case environment:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewEnvironmentAttrReader(row[zone], row[environment], "").Responses())
+		}
+	case model:
+		return func(row map[string]string) (map[string]string, error) {
+			return attrMap(c.NewModelAttrReader(row[model], "").Responses())
+		}
+	}
+
+	return nil
+}
+
+type attrResponse interface {
+	GetName() string
+	GetValue() string
+}
+
+func attrMap[T attrResponse](seq iter.Seq2[T, error]) (map[string]string, error) {
+	attrs := make(map[string]string)
+
+	for resp, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		attrs[resp.GetName()] = resp.GetValue()
+	}
+
+	return attrs, nil
+}
+
+// confirm asks a yes or no question on the terminal, the answer defaults
+// to no. It fails if stdin is not a terminal.
+func confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) { //nolint:gosec
+		return false, errors.New("cannot confirm, stdin is not a terminal")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// removeMatches shows the objects a remove glob matches and, after
+// confirming or with --yes, deletes them one at a time with del, given a glob
+// that matches only that name. With --dry-run, or if nothing matches, nothing
+// is deleted.
+//
+// For objects with no parent to narrow the glob, every lists all of them on
+// the server, and a glob with wildcards that matches every one needs --all,
+// so stack remove zone '*' is refused while removing the only zone by name
+// is not. Every is nil for the others.
+func removeMatches[T interface{ GetName() string }](f *removeFlags, object, glob string, matches, every iter.Seq2[T, error], del func(name string) error) error {
+	resps, err := collect(matches)
+	if err != nil {
+		return err
+	}
+
+	if len(resps) == 0 {
+		fmt.Printf("No %ss match %q.\n", object, glob)
+
+		return nil
+	}
+
+	if every != nil && !f.all.Val() && hasWildcard(glob) {
+		all, err := collect(every)
+		if err != nil {
+			return err
+		}
+
+		if len(resps) == len(all) {
+			return fmt.Errorf("refusing to remove every %s, use --all", object)
+		}
+	}
+
+	for _, resp := range resps {
+		fmt.Println(resp.GetName())
+	}
+
+	what := fmt.Sprintf("%d %s", len(resps), object)
+	if len(resps) > 1 {
+		what += "s"
+	}
+
+	if f.dryRun.Val() {
+		fmt.Printf("Would remove %s.\n", what)
+
+		return nil
+	}
+
+	if !f.yes.Val() {
+		ok, err := confirm("Remove " + what + "?")
+		if err != nil {
+			return fmt.Errorf("%w, use --yes to remove without confirming", err)
+		}
+
+		if !ok {
+			return errors.New("remove cancelled")
+		}
+	}
+
+	if err := f.backup("remove " + object); err != nil {
+		return err
+	}
+
+	// The names are deleted rather than the glob, which could match objects
+	// added since it was shown.
+	for _, resp := range resps {
+		name, err := globQuote(resp.GetName())
+		if err != nil {
+			return err
+		}
+
+		if err := del(name); err != nil {
+			return fmt.Errorf("remove %s %s: %w", object, resp.GetName(), err)
+		}
+	}
+
+	fmt.Printf("Removed %s.\n", what)
+
+	return nil
+}
+
+// globQuote returns a glob that matches only name, for deleting one object
+// with an RPC that takes a glob. The glob metacharacters are bracketed, [*]
+// for *, which every flavor of glob reads as the character itself. A
+// backslash escapes in some flavors and not others, so names with one are
+// refused.
+func globQuote(name string) (string, error) {
+	if strings.Contains(name, `\`) {
+		return "", fmt.Errorf("cannot delete %q by name, it has a backslash", name)
+	}
+
+	var b strings.Builder
+
+	for _, r := range name {
+		if r == '*' || r == '?' || r == '[' {
+			b.WriteByte('[')
+			b.WriteRune(r)
+			b.WriteByte(']')
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String(), nil
+}
+
+// hasWildcard reports whether glob can match more than one name. Brackets
+// around a single character, as globQuote writes them, match only it.
+func hasWildcard(glob string) bool {
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*', '?':
+			return true
+		case '[':
+			_, size := utf8.DecodeRuneInString(glob[i+1:])
+			if size == 0 || !strings.HasPrefix(glob[i+1+size:], "]") {
+				return true
+			}
+
+			i += size + 1
+		}
+	}
+
+	return false
+}
+
+// useColor reports whether to color output to f, a terminal unless
+// $NO_COLOR is set.
+func useColor(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	return term.IsTerminal(int(f.Fd())) //nolint:gosec
+}
+
 func Ptr[T any](t T) *T {
 	return &t
 }
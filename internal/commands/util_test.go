@@ -0,0 +1,33 @@
+package commands
+
+import "testing"
+
+func TestHasWildcard(t *testing.T) {
+	tests := map[string]bool{
+		"east":     false,
+		"e[*]st":   false,
+		"r[?][[]1": false,
+		"*":        true,
+		"east-?":   true,
+		"r[12]":    true,
+		"r[":       true,
+	}
+
+	for glob, want := range tests {
+		if got := hasWildcard(glob); got != want {
+			t.Errorf("hasWildcard(%q) = %v, want %v", glob, got, want)
+		}
+	}
+
+	// Every name globQuote writes matches only itself.
+	for _, name := range []string{"east", "a*b", "[x]?"} {
+		glob, err := globQuote(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if hasWildcard(glob) {
+			t.Errorf("hasWildcard(globQuote(%q)) = true", name)
+		}
+	}
+}
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _VerbName = "adddumplistloadremovereportset"
+const _VerbName = "addapplybackupdiffdumplintlistloadremovereportsetuiundo"
 
-var _VerbIndex = [...]uint8{0, 3, 7, 11, 15, 21, 27, 30}
+var _VerbIndex = [...]uint8{0, 3, 8, 14, 18, 22, 26, 30, 34, 40, 46, 49, 51, 55}
 
-const _VerbLowerName = "adddumplistloadremovereportset"
+const _VerbLowerName = "addapplybackupdiffdumplintlistloadremovereportsetuiundo"
 
 func (i Verb) String() string {
 	if i < 0 || i >= Verb(len(_VerbIndex)-1) {
@@ -25,41 +25,65 @@ func (i Verb) String() string {
 func _VerbNoOp() {
 	var x [1]struct{}
 	_ = x[Add-(0)]
-	_ = x[Dump-(1)]
-	_ = x[List-(2)]
-	_ = x[Load-(3)]
-	_ = x[Remove-(4)]
-	_ = x[Report-(5)]
-	_ = x[Set-(6)]
+	_ = x[Apply-(1)]
+	_ = x[Backup-(2)]
+	_ = x[Diff-(3)]
+	_ = x[Dump-(4)]
+	_ = x[Lint-(5)]
+	_ = x[List-(6)]
+	_ = x[Load-(7)]
+	_ = x[Remove-(8)]
+	_ = x[Report-(9)]
+	_ = x[Set-(10)]
+	_ = x[UI-(11)]
+	_ = x[Undo-(12)]
 }
 
-var _VerbValues = []Verb{Add, Dump, List, Load, Remove, Report, Set}
+var _VerbValues = []Verb{Add, Apply, Backup, Diff, Dump, Lint, List, Load, Remove, Report, Set, UI, Undo}
 
 var _VerbNameToValueMap = map[string]Verb{
 	_VerbName[0:3]:        Add,
 	_VerbLowerName[0:3]:   Add,
-	_VerbName[3:7]:        Dump,
-	_VerbLowerName[3:7]:   Dump,
-	_VerbName[7:11]:       List,
-	_VerbLowerName[7:11]:  List,
-	_VerbName[11:15]:      Load,
-	_VerbLowerName[11:15]: Load,
-	_VerbName[15:21]:      Remove,
-	_VerbLowerName[15:21]: Remove,
-	_VerbName[21:27]:      Report,
-	_VerbLowerName[21:27]: Report,
-	_VerbName[27:30]:      Set,
-	_VerbLowerName[27:30]: Set,
+	_VerbName[3:8]:        Apply,
+	_VerbLowerName[3:8]:   Apply,
+	_VerbName[8:14]:       Backup,
+	_VerbLowerName[8:14]:  Backup,
+	_VerbName[14:18]:      Diff,
+	_VerbLowerName[14:18]: Diff,
+	_VerbName[18:22]:      Dump,
+	_VerbLowerName[18:22]: Dump,
+	_VerbName[22:26]:      Lint,
+	_VerbLowerName[22:26]: Lint,
+	_VerbName[26:30]:      List,
+	_VerbLowerName[26:30]: List,
+	_VerbName[30:34]:      Load,
+	_VerbLowerName[30:34]: Load,
+	_VerbName[34:40]:      Remove,
+	_VerbLowerName[34:40]: Remove,
+	_VerbName[40:46]:      Report,
+	_VerbLowerName[40:46]: Report,
+	_VerbName[46:49]:      Set,
+	_VerbLowerName[46:49]: Set,
+	_VerbName[49:51]:      UI,
+	_VerbLowerName[49:51]: UI,
+	_VerbName[51:55]:      Undo,
+	_VerbLowerName[51:55]: Undo,
 }
 
 var _VerbNames = []string{
 	_VerbName[0:3],
-	_VerbName[3:7],
-	_VerbName[7:11],
-	_VerbName[11:15],
-	_VerbName[15:21],
-	_VerbName[21:27],
-	_VerbName[27:30],
+	_VerbName[3:8],
+	_VerbName[8:14],
+	_VerbName[14:18],
+	_VerbName[18:22],
+	_VerbName[22:26],
+	_VerbName[26:30],
+	_VerbName[30:34],
+	_VerbName[34:40],
+	_VerbName[40:46],
+	_VerbName[46:49],
+	_VerbName[49:51],
+	_VerbName[51:55],
 }
 
 // VerbString retrieves an enum value from the enum constants string name.
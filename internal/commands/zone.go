@@ -1,25 +1,32 @@
 package commands
 
 import (
+	"os"
+
 	"github.com/spf13/cobra"
 
 	"endobit.io/metal"
 	"endobit.io/metal-cli/internal/flags"
+	"endobit.io/metal-cli/internal/report"
 	pb "endobit.io/metal/gen/go/proto/metal/v1"
-	"endobit.io/table"
 )
 
 type Zone struct {
 	Client       *metal.Client
+	listFlags    *listFlags
+	removeFlags  *removeFlags
 	renameFlag   flags.Rename
 	timeZoneFlag flags.TimeZone
 	templateFlag flags.Template
 }
 
 type ZoneAttr struct {
-	Client     *metal.Client
-	renameFlag flags.Rename
-	zoneFlag   flags.Zone
+	Client      *metal.Client
+	listFlags   *listFlags
+	removeFlags *removeFlags
+	renameFlag  flags.Rename
+	zoneFlag    flags.Zone
+	valueFlags  attrValueFlags
 }
 
 func (z *Zone) New(verb Verb) *cobra.Command {
@@ -71,6 +78,20 @@ func (z *Zone) New(verb Verb) *cobra.Command {
 				return z.remove(args[0])
 			},
 		}
+	case Report:
+		cmd = cobra.Command{
+			Use:   zone + " [glob]",
+			Short: "Report one or more " + zone + "s",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(_ *cobra.Command, args []string) error {
+				var glob string
+
+				if len(args) > 0 {
+					glob = args[0]
+				}
+				return z.report(glob)
+			},
+		}
 	}
 
 	if verb == Add || verb == Set {
@@ -83,8 +104,14 @@ func (z *Zone) New(verb Verb) *cobra.Command {
 
 	if verb == Report {
 		z.templateFlag.Add(cmd.Flags(), zone)
+		z.templateFlag.Required(cmd.Flags())
+
+		return &cmd
 	}
 
+	attr := ZoneAttr{Client: z.Client, listFlags: z.listFlags, removeFlags: z.removeFlags}
+	cmd.AddCommand(attr.New(verb))
+
 	return &cmd
 }
 
@@ -99,8 +126,10 @@ func (z *Zone) create(zone string) error {
 
 func (z *Zone) list(glob string) error {
 	type row struct{ Zone, TimeZone string }
-	t := table.New()
-	defer t.Flush()
+	t, err := z.listFlags.newWriter(z.Client, zone)
+	if err != nil {
+		return err
+	}
 
 	r := z.Client.NewZoneReader(glob)
 
@@ -112,10 +141,21 @@ func (z *Zone) list(glob string) error {
 		_ = t.Write(row{
 			Zone:     resp.GetName(),
 			TimeZone: resp.GetTimeZone(),
-		})
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
+}
+
+func (z *Zone) report(glob string) error {
+	zones, err := collect(z.Client.NewZoneReader(glob).Responses())
+	if err != nil {
+		return err
+	}
+
+	r := report.Renderer{Client: z.Client, Object: zone}
+
+	return r.Render(os.Stdout, z.templateFlag.Val(), zones)
 }
 
 func (z *Zone) update(zone string) error {
@@ -133,12 +173,17 @@ func (z *Zone) update(zone string) error {
 }
 
 func (z *Zone) remove(glob string) error {
-	var req pb.DeleteZonesRequest
+	matches := z.Client.NewZoneReader(glob).Responses()
+	every := z.Client.NewZoneReader("").Responses()
 
-	req.SetGlob(glob)
-	_, err := z.Client.Metal.DeleteZones(z.Client.Context(), &req)
+	return removeMatches(z.removeFlags, zone, glob, matches, every, func(name string) error {
+		var req pb.DeleteZonesRequest
 
-	return err
+		req.SetGlob(name)
+		_, err := z.Client.Metal.DeleteZones(z.Client.Context(), &req)
+
+		return err
+	})
 }
 
 func (a *ZoneAttr) New(verb Verb) *cobra.Command {
@@ -198,6 +243,10 @@ func (a *ZoneAttr) New(verb Verb) *cobra.Command {
 		a.zoneFlag.Required(cmd.Flags())
 	}
 
+	if verb == Add || verb == Set {
+		a.valueFlags.Add(&cmd, attribute)
+	}
+
 	if verb == Set {
 		a.renameFlag.Add(cmd.Flags(), zone)
 	}
@@ -217,9 +266,14 @@ func (a *ZoneAttr) create(attr string) error {
 }
 
 func (a *ZoneAttr) list(glob string) error {
-	type row struct{ Zone, Attr, Value string }
-	t := table.New()
-	defer t.Flush()
+	type row struct {
+		Zone, Attr string
+		Value      attrText
+	}
+	t, err := a.listFlags.newWriter(a.Client, attribute)
+	if err != nil {
+		return err
+	}
 
 	r := a.Client.NewZoneAttrReader(a.zoneFlag.Val(), glob)
 
@@ -231,34 +285,44 @@ func (a *ZoneAttr) list(glob string) error {
 		_ = t.Write(row{
 			Zone:  resp.GetZone(),
 			Attr:  resp.GetName(),
-			Value: resp.GetValue(),
-		})
+			Value: attrText(resp.GetValue()),
+		}, resp)
 	}
 
-	return nil
+	return t.Flush()
 }
 
 func (a *ZoneAttr) update(attr string) error {
+	value, err := a.valueFlags.Ptr()
+	if err != nil {
+		return err
+	}
+
 	req := pb.UpdateZoneAttrRequest_builder{
 		Zone: a.zoneFlag.Ptr(),
 		Name: &attr,
 		Fields: pb.UpdateZoneAttrRequest_Fields_builder{
-			Name: a.renameFlag.Ptr(),
+			Name:  a.renameFlag.Ptr(),
+			Value: value,
 		}.Build(),
 	}.Build()
 
-	_, err := a.Client.Metal.UpdateZoneAttr(a.Client.Context(), req)
+	_, err = a.Client.Metal.UpdateZoneAttr(a.Client.Context(), req)
 
 	return err
 }
 
 func (a *ZoneAttr) remove(glob string) error {
-	req := pb.DeleteZoneAttrsRequest_builder{
-		Zone: a.zoneFlag.Ptr(),
-		Glob: &glob,
-	}.Build()
+	matches := a.Client.NewZoneAttrReader(a.zoneFlag.Val(), glob).Responses()
 
-	_, err := a.Client.Metal.DeleteZoneAttrs(a.Client.Context(), req)
+	return removeMatches(a.removeFlags, zone+" "+attribute, glob, matches, nil, func(name string) error {
+		req := pb.DeleteZoneAttrsRequest_builder{
+			Zone: a.zoneFlag.Ptr(),
+			Glob: &name,
+		}.Build()
 
-	return err
+		_, err := a.Client.Metal.DeleteZoneAttrs(a.Client.Context(), req)
+
+		return err
+	})
 }
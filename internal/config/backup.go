@@ -0,0 +1,22 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// BackupDir returns the directory of the key's schema backups, in the user's
+// state directory, $XDG_STATE_HOME or ~/.local/state.
+func BackupDir(key string) (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(home, ".local", "state")
+	}
+
+	return filepath.Join(dir, "stack", "backups", unsafeChars.ReplaceAllString(key, "_")), nil
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// CompletionDir returns the directory of the key's cached shell completions,
+// in the user's cache directory.
+func CompletionDir(key string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "stack", "completion", unsafeChars.ReplaceAllString(key, "_")), nil
+}
@@ -0,0 +1,274 @@
+// Package config reads and writes the stack configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"endobit.io/metal-cli/internal/backup"
+)
+
+// Context is a named set of connection settings and defaults.
+type Context struct {
+	Server          string `yaml:"server,omitempty"`
+	CAFile          string `yaml:"ca_file,omitempty"`
+	Cert            string `yaml:"cert,omitempty"`
+	Key             string `yaml:"key,omitempty"`
+	ServerName      string `yaml:"server_name,omitempty"`
+	Insecure        bool   `yaml:"insecure,omitempty"`
+	Plaintext       bool   `yaml:"plaintext,omitempty"`
+	Username        string `yaml:"username,omitempty"`
+	PasswordCommand string `yaml:"password_command,omitempty"` // run by sh, prints the password
+	Zone            string `yaml:"zone,omitempty"`
+	Cluster         string `yaml:"cluster,omitempty"`
+	Backup          string `yaml:"backup,omitempty"`      // auto, git or off
+	BackupKeep      int    `yaml:"backup_keep,omitempty"` // number of backups kept
+}
+
+type Config struct {
+	CurrentContext string              `yaml:"current_context,omitempty"`
+	Contexts       map[string]*Context `yaml:"contexts,omitempty"`
+
+	path string
+}
+
+// Keys are the context settings that can be changed with Set, in the order
+// they are shown to users.
+var Keys = []string{
+	"server", "ca_file", "cert", "key", "server_name", "insecure", "plaintext",
+	"username", "password_command", "zone", "cluster", "backup", "backup_keep",
+}
+
+// DefaultPath returns $STACK_CONFIG, or config.yaml in the user's stack
+// configuration directory.
+func DefaultPath() (string, error) {
+	if p := os.Getenv("STACK_CONFIG"); p != "" {
+		return p, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "stack", "config.yaml"), nil
+}
+
+// Load reads the configuration file at path, or at DefaultPath if path is
+// empty. A missing file is an empty configuration.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		p, err := DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+
+		path = p
+	}
+
+	cfg := Config{path: path}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// Save writes the configuration back to the file it was loaded from.
+func (c *Config) Save() error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, b, 0o600)
+}
+
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Names returns the context names in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Context returns a copy of the named context with the STACK_*
+// environment variables layered on top. An empty name selects
+// $STACK_CONTEXT, then the current context. It is not an error for no
+// context to be selected, the result is then built from the environment
+// alone.
+func (c *Config) Context(name string) (Context, error) {
+	var ctx Context
+
+	if name = c.Resolve(name); name != "" {
+		p, ok := c.Contexts[name]
+		if !ok {
+			return ctx, fmt.Errorf("context %q not found in %s", name, c.path)
+		}
+
+		ctx = *p
+	}
+
+	if err := ctx.applyEnv(); err != nil {
+		return ctx, err
+	}
+
+	return ctx, nil
+}
+
+// Resolve returns the name of the context selected by name, which may be
+// empty.
+func (c *Config) Resolve(name string) string {
+	if name == "" {
+		name = os.Getenv("STACK_CONTEXT")
+	}
+
+	if name == "" {
+		name = c.CurrentContext
+	}
+
+	return name
+}
+
+func (c *Config) UseContext(name string) error {
+	if _, ok := c.Contexts[name]; !ok {
+		return fmt.Errorf("context %q not found in %s", name, c.path)
+	}
+
+	c.CurrentContext = name
+
+	return nil
+}
+
+// Set changes a setting of the named context, creating the context if it
+// does not exist.
+func (c *Config) Set(name, key, value string) error {
+	if name == "" {
+		return errors.New("no context given and no current context set")
+	}
+
+	if c.Contexts == nil {
+		c.Contexts = make(map[string]*Context)
+	}
+
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		ctx = new(Context)
+	}
+
+	if err := ctx.Set(key, value); err != nil {
+		return err
+	}
+
+	c.Contexts[name] = ctx
+
+	if c.CurrentContext == "" {
+		c.CurrentContext = name
+	}
+
+	return nil
+}
+
+// Set changes one setting, named as in Keys.
+func (ctx *Context) Set(key, value string) error {
+	var err error
+
+	switch key {
+	case "server":
+		ctx.Server = value
+	case "ca_file":
+		ctx.CAFile = value
+	case "cert":
+		ctx.Cert = value
+	case "key":
+		ctx.Key = value
+	case "server_name":
+		ctx.ServerName = value
+	case "insecure":
+		err = setParsed(&ctx.Insecure, value, strconv.ParseBool)
+	case "plaintext":
+		err = setParsed(&ctx.Plaintext, value, strconv.ParseBool)
+	case "username":
+		ctx.Username = value
+	case "password_command":
+		ctx.PasswordCommand = value
+	case "zone":
+		ctx.Zone = value
+	case "cluster":
+		ctx.Cluster = value
+	case "backup":
+		if !slices.Contains(backup.Modes, value) {
+			err = fmt.Errorf("want one of %s", strings.Join(backup.Modes, ", "))
+
+			break
+		}
+
+		ctx.Backup = value
+	case "backup_keep":
+		err = setParsed(&ctx.BackupKeep, value, strconv.Atoi)
+	default:
+		return fmt.Errorf("unknown setting %q, must be one of %s", key, strings.Join(Keys, ", "))
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+
+	return nil
+}
+
+// setParsed sets *p to the parsed value, leaving it alone if value does not
+// parse.
+func setParsed[T any](p *T, value string, parse func(string) (T, error)) error {
+	v, err := parse(value)
+	if err != nil {
+		return err
+	}
+
+	*p = v
+
+	return nil
+}
+
+// applyEnv overrides settings with their STACK_<KEY> environment variable,
+// e.g. STACK_SERVER or STACK_CA_FILE.
+func (ctx *Context) applyEnv() error {
+	for _, key := range Keys {
+		if v, ok := os.LookupEnv("STACK_" + strings.ToUpper(key)); ok {
+			if err := ctx.Set(key, v); err != nil {
+				return fmt.Errorf("STACK_%s: %w", strings.ToUpper(key), err)
+			}
+		}
+	}
+
+	return nil
+}
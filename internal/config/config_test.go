@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContextSet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       Context
+	}{
+		{key: "server", value: "metal:443", want: Context{Server: "metal:443"}},
+		{key: "ca_file", value: "ca.pem", want: Context{CAFile: "ca.pem"}},
+		{key: "cert", value: "cert.pem", want: Context{Cert: "cert.pem"}},
+		{key: "key", value: "key.pem", want: Context{Key: "key.pem"}},
+		{key: "server_name", value: "metal", want: Context{ServerName: "metal"}},
+		{key: "insecure", value: "true", want: Context{Insecure: true}},
+		{key: "plaintext", value: "1", want: Context{Plaintext: true}},
+		{key: "username", value: "ops", want: Context{Username: "ops"}},
+		{key: "password_command", value: "pass metal", want: Context{PasswordCommand: "pass metal"}},
+		{key: "zone", value: "east", want: Context{Zone: "east"}},
+		{key: "cluster", value: "c1", want: Context{Cluster: "c1"}},
+		{key: "backup", value: "git", want: Context{Backup: "git"}},
+		{key: "backup_keep", value: "100", want: Context{BackupKeep: 100}},
+	}
+
+	for _, tt := range tests {
+		var ctx Context
+
+		if err := ctx.Set(tt.key, tt.value); err != nil {
+			t.Errorf("Set(%q, %q): %v", tt.key, tt.value, err)
+
+			continue
+		}
+
+		if ctx != tt.want {
+			t.Errorf("Set(%q, %q) = %+v, want %+v", tt.key, tt.value, ctx, tt.want)
+		}
+	}
+
+	if len(tests) != len(Keys) {
+		t.Errorf("tested %d keys, there are %d", len(tests), len(Keys))
+	}
+}
+
+func TestContextSetErrors(t *testing.T) {
+	tests := []struct{ key, value, err string }{
+		{key: "insecure", value: "maybe", err: "insecure: "},
+		{key: "plaintext", value: "", err: "plaintext: "},
+		{key: "backup", value: "always", err: "backup: want one of auto, git, off"},
+		{key: "backup_keep", value: "ten", err: "backup_keep: "},
+		{key: "port", value: "443", err: `unknown setting "port"`},
+	}
+
+	for _, tt := range tests {
+		ctx := Context{Insecure: true, Plaintext: true, Backup: "auto", BackupKeep: 5}
+		want := ctx
+
+		err := ctx.Set(tt.key, tt.value)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+			t.Errorf("Set(%q, %q) error %v, want %q...", tt.key, tt.value, err, tt.err)
+		}
+
+		if ctx != want {
+			t.Errorf("Set(%q, %q) changed the context to %+v", tt.key, tt.value, ctx)
+		}
+	}
+}
+
+func TestContextEnv(t *testing.T) {
+	cfg := Config{
+		CurrentContext: "lab",
+		Contexts: map[string]*Context{
+			"lab":  {Server: "lab:443", Username: "admin", Zone: "east"},
+			"prod": {Server: "prod:443"},
+		},
+	}
+
+	t.Setenv("STACK_CONTEXT", "")
+	t.Setenv("STACK_SERVER", "env:443")
+	t.Setenv("STACK_INSECURE", "true")
+	t.Setenv("STACK_ZONE", "west")
+
+	ctx, err := cfg.Context("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Context{Server: "env:443", Insecure: true, Username: "admin", Zone: "west"}
+	if ctx != want {
+		t.Errorf("got %+v, want %+v", ctx, want)
+	}
+
+	if cfg.Contexts["lab"].Server != "lab:443" {
+		t.Error("the environment changed the stored context")
+	}
+
+	t.Setenv("STACK_CONTEXT", "prod")
+
+	if ctx, _ = cfg.Context(""); ctx.Zone != "west" || ctx.Username != "" {
+		t.Errorf("STACK_CONTEXT=prod got %+v", ctx)
+	}
+
+	t.Setenv("STACK_BACKUP_KEEP", "lots")
+
+	if _, err := cfg.Context("lab"); err == nil || !strings.HasPrefix(err.Error(), "STACK_BACKUP_KEEP: ") {
+		t.Errorf("bad STACK_BACKUP_KEEP error %v", err)
+	}
+
+	if _, err := cfg.Context("dev"); err == nil {
+		t.Error("no error for a missing context")
+	}
+}
+
+func TestConfigSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stack", "config.yaml")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Set("lab", "server", "lab:443"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Set("lab", "backup_keep", "7"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("saved file %v, %v", fi, err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.CurrentContext != "lab" || !reflect.DeepEqual(got.Contexts, cfg.Contexts) {
+		t.Errorf("loaded %+v, want %+v", got, cfg)
+	}
+}
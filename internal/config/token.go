@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Token is a cached authentication token for one context.
+type Token struct {
+	Server   string    `yaml:"server"`
+	Username string    `yaml:"username"`
+	Token    string    `yaml:"token"`
+	Expiry   time.Time `yaml:"expiry,omitempty"`
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// NewToken returns a Token, taking the expiry from the token itself when
+// it is a JWT.
+func NewToken(server, username, token string) *Token {
+	return &Token{
+		Server:   server,
+		Username: username,
+		Token:    token,
+		Expiry:   jwtExpiry(token),
+	}
+}
+
+// Expired reports whether the token has expired, or will within a minute.
+// Tokens without a known expiry never expire.
+func (t *Token) Expired() bool {
+	return !t.Expiry.IsZero() && time.Until(t.Expiry) < time.Minute
+}
+
+// Matches reports whether the token was issued to username on server.
+func (t *Token) Matches(server, username string) bool {
+	return t.Server == server && t.Username == username
+}
+
+// LoadToken returns the cached token for the key, or nil if there is none.
+func LoadToken(key string) (*Token, error) {
+	path, err := tokenPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var t Token
+
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// SaveToken caches the token for the key, readable only by the user.
+func SaveToken(key string, t *Token) error {
+	path, err := tokenPath(key)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, b, 0o600)
+}
+
+// DeleteToken removes the cached token for the key, if any.
+func DeleteToken(key string) error {
+	path, err := tokenPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func tokenPath(key string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "stack", "tokens", unsafeChars.ReplaceAllString(key, "_")+".yaml"), nil
+}
+
+// jwtExpiry returns the exp claim of a JWT without verifying it, the
+// server does that. Anything else has no expiry.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	if json.Unmarshal(b, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(claims.Exp, 0)
+}
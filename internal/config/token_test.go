@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTokenMatches(t *testing.T) {
+	tok := NewToken("metal:443", "admin", "opaque")
+
+	tests := []struct {
+		server, username string
+		want             bool
+	}{
+		{server: "metal:443", username: "admin", want: true},
+		{server: "metal:443", username: "ops"},
+		{server: "other:443", username: "admin"},
+	}
+
+	for _, tt := range tests {
+		if got := tok.Matches(tt.server, tt.username); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.server, tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	jwt := func(claims string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+	}
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		token   string
+		expiry  time.Time
+		expired bool
+	}{
+		{token: "opaque"},
+		{token: jwt(`{"sub":"admin"}`)},
+		{token: "a.!!!.c"},
+		{token: jwt(`not json`)},
+		{token: jwt(`{"exp":` + strconv.FormatInt(exp.Unix(), 10) + `}`), expiry: exp},
+		{token: jwt(`{"exp":` + strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10) + `}`), expired: true},
+		{token: jwt(`{"exp":1}`), expiry: time.Unix(1, 0), expired: true},
+	}
+
+	for _, tt := range tests {
+		tok := NewToken("metal:443", "admin", tt.token)
+
+		if !tt.expiry.IsZero() && !tok.Expiry.Equal(tt.expiry) {
+			t.Errorf("%s: expiry %v, want %v", tt.token, tok.Expiry, tt.expiry)
+		}
+
+		if tt.expiry.IsZero() && !tt.expired && !tok.Expiry.IsZero() {
+			t.Errorf("%s: expiry %v, want none", tt.token, tok.Expiry)
+		}
+
+		if got := tok.Expired(); got != tt.expired {
+			t.Errorf("%s: Expired() = %v, want %v", tt.token, got, tt.expired)
+		}
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	key := "lab/east:443"
+
+	if tok, err := LoadToken(key); err != nil || tok != nil {
+		t.Fatalf("LoadToken before saving = %v, %v", tok, err)
+	}
+
+	want := NewToken("metal:443", "admin", "opaque")
+
+	if err := SaveToken(key, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadToken(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got == nil || *got != *want {
+		t.Errorf("LoadToken = %+v, want %+v", got, want)
+	}
+
+	if err := DeleteToken(key); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteToken(key); err != nil {
+		t.Errorf("deleting a missing token: %v", err)
+	}
+}
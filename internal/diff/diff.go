@@ -0,0 +1,309 @@
+// Package diff compares documents decoded from JSON, such as schemas, and
+// prints the changes, or a unified diff of the documents' text.
+//
+// Lists of objects that all have a "name" are matched by name, so the path
+// of a host reads zones[east].hosts[node-1] and reordering is not a change.
+// Any other list is compared as a whole.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+type Op int
+
+const (
+	Added Op = iota
+	Changed
+	Removed
+)
+
+func (o Op) String() string {
+	switch o {
+	case Added:
+		return "added"
+	case Changed:
+		return "changed"
+	case Removed:
+		return "removed"
+	}
+
+	return fmt.Sprintf("Op(%d)", int(o))
+}
+
+func (o Op) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// Change is one difference. Old is nil when added and New is nil when
+// removed.
+type Change struct {
+	Op   Op     `json:"op"`
+	Path string `json:"path"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// Formats are the forms changes can be written in, by Write or, for a patch,
+// by Patch.
+var Formats = []string{"human", "json", "patch"}
+
+// Compare returns the changes that turn from into to. Without prune, values
+// missing from to are not changes, to is applied over from as a patch.
+func Compare(from, to any, prune bool) []Change {
+	var changes []Change
+
+	compare(&changes, "", from, to, prune)
+
+	return changes
+}
+
+func compare(changes *[]Change, path string, from, to any, prune bool) {
+	if om, ok := from.(map[string]any); ok {
+		if nm, ok := to.(map[string]any); ok {
+			for _, k := range keys(om, nm) {
+				o, inOld := om[k]
+				n, inNew := nm[k]
+
+				p := join(path, k)
+
+				switch {
+				case !inOld:
+					*changes = append(*changes, Change{Op: Added, Path: p, New: n})
+				case !inNew:
+					if prune {
+						*changes = append(*changes, Change{Op: Removed, Path: p, Old: o})
+					}
+				default:
+					compare(changes, p, o, n, prune)
+				}
+			}
+
+			return
+		}
+	}
+
+	if ol, ok := named(from); ok {
+		if nl, ok := named(to); ok {
+			for _, name := range names(ol, nl) {
+				o, inOld := ol.get(name)
+				n, inNew := nl.get(name)
+
+				p := path + "[" + name + "]"
+
+				switch {
+				case !inOld:
+					*changes = append(*changes, Change{Op: Added, Path: p, New: n})
+				case !inNew:
+					if prune {
+						*changes = append(*changes, Change{Op: Removed, Path: p, Old: o})
+					}
+				default:
+					compare(changes, p, o, n, prune)
+				}
+			}
+
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, Change{Op: Changed, Path: path, Old: from, New: to})
+	}
+}
+
+// named returns a list of objects by name, in list order, if every element
+// has a unique string name.
+func named(v any) (*namedList, bool) {
+	l, ok := v.([]any)
+	if !ok || len(l) == 0 {
+		return nil, false
+	}
+
+	nl := namedList{byName: make(map[string]any, len(l))}
+
+	for _, e := range l {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+
+		if _, dup := nl.byName[name]; dup {
+			return nil, false
+		}
+
+		nl.byName[name] = e
+		nl.order = append(nl.order, name)
+	}
+
+	return &nl, true
+}
+
+type namedList struct {
+	order  []string
+	byName map[string]any
+}
+
+func (l *namedList) get(name string) (any, bool) {
+	v, ok := l.byName[name]
+
+	return v, ok
+}
+
+// names returns the names in to's order followed by those only in from.
+func names(from, to *namedList) []string {
+	s := slices.Clone(to.order)
+
+	for _, name := range from.order {
+		if _, ok := to.byName[name]; !ok {
+			s = append(s, name)
+		}
+	}
+
+	return s
+}
+
+func keys(a, b map[string]any) []string {
+	var s []string
+
+	for k := range a {
+		s = append(s, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			s = append(s, k)
+		}
+	}
+
+	slices.Sort(s)
+
+	return s
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+const (
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	cyan   = "\x1b[36m"
+	reset  = "\x1b[0m"
+)
+
+// Print writes the changes, one header per change followed by the removed
+// and added values, and a summary line. Color adds ANSI colors.
+func Print(w io.Writer, changes []Change, color bool) error {
+	p := printer{w: w, color: color}
+
+	var added, changed, removed int
+
+	for _, c := range changes {
+		switch c.Op {
+		case Added:
+			added++
+			p.line(green, "+ "+c.Path)
+			p.value(green, "+", c.New)
+		case Changed:
+			changed++
+			p.line(yellow, "~ "+c.Path)
+			p.value(red, "-", c.Old)
+			p.value(green, "+", c.New)
+		case Removed:
+			removed++
+			p.line(red, "- "+c.Path)
+			p.value(red, "-", c.Old)
+		}
+	}
+
+	p.line("", fmt.Sprintf("%d to add, %d to change, %d to remove.", added, changed, removed))
+
+	return p.err
+}
+
+// Write writes the changes as human or json. A patch is not made from the
+// changes but from the documents, see Patch.
+func Write(w io.Writer, format string, changes []Change, color bool) error {
+	switch format {
+	case "", "human":
+		return Print(w, changes, color)
+
+	case "json":
+		if changes == nil {
+			changes = []Change{}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(changes)
+	}
+
+	return fmt.Errorf("unknown diff format %q, want human or json", format)
+}
+
+type printer struct {
+	w     io.Writer
+	color bool
+	err   error
+}
+
+func (p *printer) line(color, s string) {
+	if p.err != nil {
+		return
+	}
+
+	if p.color && color != "" {
+		s = color + s + reset
+	}
+
+	_, p.err = fmt.Fprintln(p.w, s)
+}
+
+// value prints v as indented YAML, each line marked with sign.
+func (p *printer) value(color, sign string, v any) {
+	for _, s := range strings.Split(Text(v), "\n") {
+		p.line(color, sign+"   "+s)
+	}
+}
+
+// Text is v as YAML without the trailing newline, or as a quoted string for
+// strings that YAML would not show plainly.
+func Text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if v == "" || strings.ContainsAny(v, "\n:#") || strings.TrimSpace(v) != v {
+			return strconv.Quote(v)
+		}
+
+		return v
+	}
+
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return strings.TrimSuffix(string(b), "\n")
+}
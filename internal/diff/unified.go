@@ -0,0 +1,234 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// context is the number of unchanged lines around each change in a patch.
+const context = 3
+
+// edit is one line of an edit script: kept (' '), removed ('-') or added
+// ('+'). A and b are the line's index in each side, or for a line only in
+// one side, where it falls in the other.
+type edit struct {
+	op   byte
+	a, b int
+}
+
+// Patch writes a unified diff of two documents, such as the YAML dumps of two
+// schemas, that patch(1) can apply. From and to name the two sides. Nothing is
+// written if the documents are the same.
+func Patch(w io.Writer, a, b []byte, from, to string, color bool) error {
+	x, y := lines(a), lines(b)
+
+	edits := lineEdits(x, y)
+	if !slices.ContainsFunc(edits, func(e edit) bool { return e.op != ' ' }) {
+		return nil
+	}
+
+	p := printer{w: w, color: color}
+
+	p.line("", "--- "+from)
+	p.line("", "+++ "+to)
+
+	for i := 0; ; {
+		for i < len(edits) && edits[i].op == ' ' {
+			i++
+		}
+
+		if i == len(edits) {
+			break
+		}
+
+		// Changes closer than twice the context share a hunk.
+		end := i
+
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+
+		stop := min(end+context, len(edits))
+		hunk := edits[max(i-context, 0):stop]
+
+		var na, nb int
+
+		for _, e := range hunk {
+			if e.op != '+' {
+				na++
+			}
+
+			if e.op != '-' {
+				nb++
+			}
+		}
+
+		p.line(cyan, fmt.Sprintf("@@ -%s +%s @@", span(hunk[0].a, na), span(hunk[0].b, nb)))
+
+		for _, e := range hunk {
+			switch e.op {
+			case ' ':
+				p.line("", " "+x[e.a])
+			case '-':
+				p.line(red, "-"+x[e.a])
+			case '+':
+				p.line(green, "+"+y[e.b])
+			}
+		}
+
+		i = stop
+	}
+
+	return p.err
+}
+
+// span is a hunk's range of lines in one side, from the 0-based index of its
+// first line. An empty range starts at the line before it.
+func span(start, n int) string {
+	switch n {
+	case 0:
+		return strconv.Itoa(start) + ",0"
+	case 1:
+		return strconv.Itoa(start + 1)
+	}
+
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(n)
+}
+
+func lines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// lineEdits is the shortest edit script turning a into b, by Myers'
+// algorithm in its linear space form: the middle snake of the shortest path
+// splits the problem in two, which are solved the same way, so only two
+// vectors of furthest points are kept rather than one per round.
+func lineEdits(a, b []string) []edit {
+	edits := make([]edit, 0, max(len(a), len(b)))
+
+	var walk func(a0, a1, b0, b1 int)
+
+	walk = func(a0, a1, b0, b1 int) {
+		for a0 < a1 && b0 < b1 && a[a0] == b[b0] {
+			edits = append(edits, edit{' ', a0, b0})
+			a0++
+			b0++
+		}
+
+		var n int // lines in common at the end
+
+		for a1-n > a0 && b1-n > b0 && a[a1-n-1] == b[b1-n-1] {
+			n++
+		}
+
+		a1 -= n
+		b1 -= n
+
+		switch {
+		case a0 == a1:
+			for j := b0; j < b1; j++ {
+				edits = append(edits, edit{'+', a0, j})
+			}
+		case b0 == b1:
+			for i := a0; i < a1; i++ {
+				edits = append(edits, edit{'-', i, b0})
+			}
+		default:
+			x, y, u, v := middleSnake(a[a0:a1], b[b0:b1])
+
+			walk(a0, a0+x, b0, b0+y)
+
+			for i := range u - x {
+				edits = append(edits, edit{' ', a0 + x + i, b0 + y + i})
+			}
+
+			walk(a0+u, a1, b0+v, b1)
+		}
+
+		for i := range n {
+			edits = append(edits, edit{' ', a1 + i, b1 + i})
+		}
+	}
+
+	walk(0, len(a), 0, len(b))
+
+	return edits
+}
+
+// middleSnake returns the start, x and y, and end, u and v, of the snake in
+// the middle of a shortest edit script, found by searching from both ends at
+// once until the furthest points meet. A and b must differ.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+
+	half := (n + m + 1) / 2
+	off := half + 1
+
+	// Furthest x on each diagonal k = x-y, searching forward, and on each
+	// diagonal of the reversed sequences, searching backward.
+	vf := make([]int, 2*off+1)
+	vb := make([]int, 2*off+1)
+
+	for d := 0; d <= half; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+
+			y = x - k
+			u, v = x, y
+
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+
+			vf[off+k] = u
+
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && u+vb[off+r] >= n {
+				return x, y, u, v
+			}
+		}
+
+		for r := -d; r <= d; r += 2 {
+			var xr int
+
+			if r == -d || (r != d && vb[off+r-1] < vb[off+r+1]) {
+				xr = vb[off+r+1]
+			} else {
+				xr = vb[off+r-1] + 1
+			}
+
+			yr := xr - r
+			ur, vr := xr, yr
+
+			for ur < n && vr < m && a[n-ur-1] == b[m-vr-1] {
+				ur++
+				vr++
+			}
+
+			vb[off+r] = ur
+
+			if k := delta - r; !odd && k >= -d && k <= d && vf[off+k]+ur >= n {
+				return n - ur, m - vr, n - xr, m - yr
+			}
+		}
+	}
+
+	panic("diff: no middle snake")
+}
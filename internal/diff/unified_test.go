@@ -0,0 +1,203 @@
+package diff
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "same",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n",
+			b:    "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\n",
+			want: `--- a
++++ b
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`,
+		},
+		{
+			name: "joined hunks",
+			a:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			b:    "a\nB\nc\nd\ne\nf\ng\nH\n",
+			want: `--- a
++++ b
+@@ -1,8 +1,8 @@
+ a
+-b
++B
+ c
+ d
+ e
+ f
+ g
+-h
++H
+`,
+		},
+		{
+			name: "from empty",
+			b:    "a\n",
+			want: `--- a
++++ b
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name: "to empty",
+			a:    "a\nb\n",
+			want: `--- a
++++ b
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+
+			if err := Patch(&b, []byte(tt.a), []byte(tt.b), "a", "b", false); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLineEdits checks the edit scripts of random documents turn a into b
+// and are as short as the longest common subsequence allows.
+func TestLineEdits(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+
+	doc := func() []string {
+		l := make([]string, r.IntN(12))
+		for i := range l {
+			l[i] = string(rune('a' + r.IntN(4)))
+		}
+
+		return l
+	}
+
+	for range 2000 {
+		a, b := doc(), doc()
+		edits := lineEdits(a, b)
+
+		var got []string
+
+		changes, i, j := 0, 0, 0
+
+		for _, e := range edits {
+			switch e.op {
+			case ' ':
+				if e.a != i || e.b != j || a[i] != b[j] {
+					t.Fatalf("%q to %q: bad keep %+v", a, b, e)
+				}
+
+				got = append(got, a[i])
+				i++
+				j++
+			case '-':
+				if e.a != i || e.b != j {
+					t.Fatalf("%q to %q: bad remove %+v", a, b, e)
+				}
+
+				changes++
+				i++
+			case '+':
+				if e.a != i || e.b != j {
+					t.Fatalf("%q to %q: bad add %+v", a, b, e)
+				}
+
+				got = append(got, b[j])
+				changes++
+				j++
+			}
+		}
+
+		if i != len(a) || !slices.Equal(got, b) {
+			t.Fatalf("%q to %q: edits make %q", a, b, got)
+		}
+
+		if want := len(a) + len(b) - 2*lcs(a, b); changes != want {
+			t.Fatalf("%q to %q: %d changes, want %d", a, b, changes, want)
+		}
+	}
+}
+
+// TestLineEditsSpace diffs two large documents with nothing in common, which
+// must not keep a vector of furthest points per round.
+func TestLineEditsSpace(t *testing.T) {
+	a := make([]string, 5000)
+	b := make([]string, 5000)
+
+	for i := range a {
+		a[i] = "a" + strconv.Itoa(i)
+		b[i] = "b" + strconv.Itoa(i)
+	}
+
+	var before, after runtime.MemStats
+
+	runtime.ReadMemStats(&before)
+
+	edits := lineEdits(a, b)
+
+	runtime.ReadMemStats(&after)
+
+	if len(edits) != len(a)+len(b) {
+		t.Errorf("got %d edits, want %d", len(edits), len(a)+len(b))
+	}
+
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("allocated %d bytes", n)
+	}
+}
+
+func lcs(a, b []string) int {
+	l := make([][]int, len(a)+1)
+	for i := range l {
+		l[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				l[i][j] = l[i+1][j+1] + 1
+			} else {
+				l[i][j] = max(l[i+1][j], l[i][j+1])
+			}
+		}
+	}
+
+	return l[0][0]
+}
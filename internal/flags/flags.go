@@ -1,32 +1,84 @@
 package flags
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
+// diffFormats are the formats of diff output, see package diff.
+var diffFormats = []string{"human", "json", "patch"}
+
+// outputFormats are the formats of list output, see package output.
+var outputFormats = []string{
+	"table", "wide", "json", "yaml", "csv", "tsv", "name",
+	"jsonpath=TEMPLATE", "jsonpath-file=FILE", "go-template=TEMPLATE", "go-template-file=FILE",
+}
+
 type (
 	boolFlag struct {
 		value *bool
+		flag  *pflag.Flag
 	}
 
 	stringFlag struct {
 		value *string
+		flag  *pflag.Flag
 	}
 
+	stringsFlag struct {
+		value *[]string
+	}
+
+	uint32Flag struct {
+		value *uint32
+		flag  *pflag.Flag
+	}
+
+	Address     struct{ stringFlag }
+	All         struct{ boolFlag }
+	Apply       struct{ boolFlag }
 	Appliance   struct{ stringFlag }
 	Arch        struct{ stringFlag }
+	Boot        struct{ boolFlag }
 	Cluster     struct{ stringFlag }
+	Columns     struct{ stringFlag }
+	DiffFormat  struct{ stringFlag }
+	DNS         struct{ stringFlag }
+	DryRun      struct{ boolFlag }
+	Gateway     struct{ stringFlag }
 	Model       struct{ stringFlag }
 	Rack        struct{ stringFlag }
 	Environment struct{ stringFlag }
+	File        struct{ stringsFlag }
+	Format      struct{ stringFlag }
 	Host        struct{ stringFlag }
+	IP          struct{ stringFlag }
 	JSON        struct{ boolFlag }
+	Limit       struct{ uint32Flag }
+	MAC         struct{ stringFlag }
 	Make        struct{ stringFlag }
+	MTU         struct{ uint32Flag }
+	Netmask     struct{ stringFlag }
+	Network     struct{ stringFlag }
+	Output      struct{ stringFlag }
+	Primary     struct{ boolFlag }
+	Prune       struct{ boolFlag }
+	PXE         struct{ boolFlag }
 	Rename      struct{ stringFlag }
+	Reverse     struct{ boolFlag }
+	Selector    struct{ stringsFlag }
+	SortBy      struct{ stringFlag }
 	Template    struct{ stringFlag }
 	TimeZone    struct{ stringFlag }
+	Update      struct{ stringFlag }
 	Value       struct{ stringFlag }
+	ValueFile   struct{ stringFlag }
+	ValueType   struct{ stringFlag }
+	VLAN        struct{ uint32Flag }
+	Where       struct{ stringsFlag }
+	Yes         struct{ boolFlag }
 	Zone        struct{ stringFlag }
 )
 
@@ -38,7 +90,13 @@ func (b boolFlag) Val() bool {
 	return *b.value
 }
 
+// Ptr returns nil for flags that were registered but not given on the
+// command line, so an update leaves the field unchanged.
 func (b boolFlag) Ptr() *bool {
+	if b.flag != nil && !b.flag.Changed {
+		return nil
+	}
+
 	return b.value
 }
 
@@ -50,64 +108,242 @@ func (s stringFlag) Val() string {
 	return *s.value
 }
 
+// Ptr returns nil for flags that were registered but not given on the
+// command line, so an update leaves the field unchanged. A flag given as ""
+// clears the field.
 func (s stringFlag) Ptr() *string {
+	if s.flag != nil && !s.flag.Changed {
+		return nil
+	}
+
 	return s.value
 }
 
+// Changed reports whether the flag was given on the command line.
+func (s stringFlag) Changed() bool {
+	return s.flag != nil && s.flag.Changed
+}
+
+func (s stringsFlag) Val() []string {
+	if s.value == nil {
+		return nil
+	}
+
+	return *s.value
+}
+
+func (u uint32Flag) Val() uint32 {
+	if u.value == nil {
+		return 0
+	}
+
+	return *u.value
+}
+
+func (u uint32Flag) Ptr() *uint32 {
+	if u.flag != nil && !u.flag.Changed {
+		return nil
+	}
+
+	return u.value
+}
+
+// register adds a flag to flags and returns its value along with the
+// pflag.Flag, which tells Ptr and Changed whether it was given on the command
+// line.
+func register[T bool | string | uint32](flags *pflag.FlagSet, name, shorthand string, value T, usage string) (*T, *pflag.Flag) {
+	p := new(T)
+
+	switch p := any(p).(type) {
+	case *bool:
+		flags.BoolVarP(p, name, shorthand, any(value).(bool), usage)
+	case *string:
+		flags.StringVarP(p, name, shorthand, any(value).(string), usage)
+	case *uint32:
+		flags.Uint32VarP(p, name, shorthand, any(value).(uint32), usage)
+	}
+
+	return p, flags.Lookup(name)
+}
+
 func (b *boolFlag) Add(flags *pflag.FlagSet, object string) {
-	b.value = flags.Bool("json", false, "output "+object+" as JSON")
+	b.value, b.flag = register(flags, "json", "", false, "output "+object+" as JSON")
+}
+
+func (a *Address) Add(flags *pflag.FlagSet, object string) {
+	a.value, a.flag = register(flags, "address", "", "", "address (CIDR) of the "+object)
+}
+
+func (a *Apply) Add(flags *pflag.FlagSet, object string) {
+	a.value, a.flag = register(flags, "apply", "", false, "apply the "+object+" without asking")
+}
+
+func (a *All) Add(flags *pflag.FlagSet, object string) {
+	a.value, a.flag = register(flags, "all", "", false, "allow removing all the "+object)
 }
 
 func (a *Appliance) Add(flags *pflag.FlagSet, object string) {
-	a.value = flags.String("appliance", "", "appliance for the "+object)
+	a.value, a.flag = register(flags, "appliance", "", "", "appliance for the "+object)
 }
 
 func (a *Arch) Add(flags *pflag.FlagSet, object string) {
-	a.value = flags.String("arch", "", "architecture for the "+object)
+	a.value, a.flag = register(flags, "arch", "", "", "architecture for the "+object)
+}
+
+func (c *Columns) Add(flags *pflag.FlagSet, object string) {
+	c.value, c.flag = register(flags, "columns", "", "", "comma separated columns of the "+object+" to output, for example Zone,Name")
+}
+
+func (b *Boot) Add(flags *pflag.FlagSet, object string) {
+	b.value, b.flag = register(flags, "boot", "", false, "mark the "+object+" as the boot "+object)
 }
 
 func (c *Cluster) Add(flags *pflag.FlagSet, object string) {
-	c.value = flags.String("cluster", "", "cluster for the "+object)
+	c.value, c.flag = register(flags, "cluster", "", "", "cluster for the "+object)
+}
+
+func (d *DiffFormat) Add(flags *pflag.FlagSet, object string) {
+	d.value, d.flag = register(flags, "output", "o", "human",
+		"output format of the "+object+", one of "+strings.Join(diffFormats, ", "))
+}
+
+func (d *DNS) Add(flags *pflag.FlagSet, object string) {
+	d.value, d.flag = register(flags, "dns", "", "", "DNS server for the "+object)
+}
+
+func (d *DryRun) Add(flags *pflag.FlagSet, object string) {
+	d.value, d.flag = register(flags, "dry-run", "", false, "show the "+object+" without applying them")
 }
 
 func (e *Environment) Add(flags *pflag.FlagSet, object string) {
-	e.value = flags.String("environment", "", "environment for the "+object)
+	e.value, e.flag = register(flags, "environment", "", "", "environment for the "+object)
+}
+
+func (f *File) Add(flags *pflag.FlagSet, object string) {
+	f.value = flags.StringArrayP("filename", "f", nil, object+" file, directory or - for stdin")
+}
+
+func (f *Format) Add(flags *pflag.FlagSet, object string) {
+	f.value, f.flag = register(flags, "format", "", "", "format of the "+object+" read from stdin or files without an extension, json or yaml")
+}
+
+func (g *Gateway) Add(flags *pflag.FlagSet, object string) {
+	g.value, g.flag = register(flags, "gateway", "", "", "gateway for the "+object)
 }
 
 func (h *Host) Add(flags *pflag.FlagSet, object string) {
-	h.value = flags.String("host", "", "host for the "+object)
+	h.value, h.flag = register(flags, "host", "", "", "host for the "+object)
+}
+
+func (i *IP) Add(flags *pflag.FlagSet, object string) {
+	i.value, i.flag = register(flags, "ip", "", "", "IP address of the "+object)
+}
+
+func (m *MAC) Add(flags *pflag.FlagSet, object string) {
+	m.value, m.flag = register(flags, "mac", "", "", "MAC address of the "+object)
 }
 
 func (m *Make) Add(flags *pflag.FlagSet, object string) {
-	m.value = flags.String("make", "", "make for the "+object)
+	m.value, m.flag = register(flags, "make", "", "", "make for the "+object)
+}
+
+func (m *MTU) Add(flags *pflag.FlagSet, object string) {
+	m.value, m.flag = register(flags, "mtu", "", uint32(0), "MTU for the "+object)
 }
 
 func (m *Model) Add(flags *pflag.FlagSet, object string) {
-	m.value = flags.String("model", "", "model for the "+object)
+	m.value, m.flag = register(flags, "model", "", "", "model for the "+object)
+}
+
+func (n *Netmask) Add(flags *pflag.FlagSet, object string) {
+	n.value, n.flag = register(flags, "netmask", "", "", "netmask for the "+object)
+}
+
+func (l *Limit) Add(flags *pflag.FlagSet, object string) {
+	l.value, l.flag = register(flags, "limit", "", uint32(0), "maximum number of "+object+" to output")
+}
+
+func (n *Network) Add(flags *pflag.FlagSet, object string) {
+	n.value, n.flag = register(flags, "network", "", "", "network for the "+object)
+}
+
+func (o *Output) Add(flags *pflag.FlagSet, object string) {
+	o.value, o.flag = register(flags, "output", "o", "table",
+		"output format of the "+object+", one of "+strings.Join(outputFormats, ", "))
+}
+
+func (p *Primary) Add(flags *pflag.FlagSet, object string) {
+	p.value, p.flag = register(flags, "primary", "", false, "mark the "+object+" as the primary "+object)
+}
+
+func (p *Prune) Add(flags *pflag.FlagSet, object string) {
+	p.value, p.flag = register(flags, "prune", "", false, "delete objects missing from the "+object)
+}
+
+func (p *PXE) Add(flags *pflag.FlagSet, object string) {
+	p.value, p.flag = register(flags, "pxe", "", false, "enable PXE booting on the "+object)
 }
 
 func (r *Rack) Add(flags *pflag.FlagSet, object string) {
-	r.value = flags.String("rack", "", "rack for the "+object)
+	r.value, r.flag = register(flags, "rack", "", "", "rack for the "+object)
 }
 
 func (r *Rename) Add(flags *pflag.FlagSet, object string) {
-	r.value = flags.String("model", "", "rename the "+object)
+	r.value, r.flag = register(flags, "rename", "", "", "rename the "+object)
+}
+
+func (r *Reverse) Add(flags *pflag.FlagSet, object string) {
+	r.value, r.flag = register(flags, "reverse", "", false, "output "+object+" in reverse order")
+}
+
+func (s *Selector) Add(flags *pflag.FlagSet, object string) {
+	s.value = flags.StringArrayP("selector", "l", nil,
+		"select "+object+" by attr, for example role=compute,!power")
+}
+
+func (s *SortBy) Add(flags *pflag.FlagSet, object string) {
+	s.value, s.flag = register(flags, "sort-by", "", "", "column to sort the "+object+" by")
 }
 
 func (t *Template) Add(flags *pflag.FlagSet, object string) {
-	t.value = flags.String("template", "", "template for the "+object)
+	t.value, t.flag = register(flags, "template", "", "", "template for the "+object)
 }
 
 func (t *TimeZone) Add(flags *pflag.FlagSet, object string) {
-	t.value = flags.String("timezone", "", "time zone for the "+object)
+	t.value, t.flag = register(flags, "timezone", "", "", "time zone for the "+object)
+}
+
+func (u *Update) Add(flags *pflag.FlagSet, object string) {
+	u.value, u.flag = register(flags, "update", "", "", "write the "+object+" to a YAML file, keeping the comments already in it")
 }
 
 func (v *Value) Add(flags *pflag.FlagSet, object string) {
-	v.value = flags.String("value", "", "value of the "+object)
+	v.value, v.flag = register(flags, "value", "", "", "value of the "+object)
+}
+
+func (v *ValueFile) Add(flags *pflag.FlagSet, object string) {
+	v.value, v.flag = register(flags, "value-file", "", "", "read the value of the "+object+" from a file (- for stdin)")
+}
+
+func (v *ValueType) Add(flags *pflag.FlagSet, object string) {
+	v.value, v.flag = register(flags, "type", "", "string", "type of the "+object+" value (string, bool, int, list, json)")
+}
+
+func (v *VLAN) Add(flags *pflag.FlagSet, object string) {
+	v.value, v.flag = register(flags, "vlan", "", uint32(0), "VLAN for the "+object)
+}
+
+func (w *Where) Add(flags *pflag.FlagSet, object string) {
+	w.value = flags.StringArray("where", nil,
+		"select "+object+" by column or attr, for example rack=r1*,attr.os=rocky9,!attr.power")
+}
+
+func (y *Yes) Add(flags *pflag.FlagSet, object string) {
+	y.value, y.flag = register(flags, "yes", "y", false, "skip confirming the "+object)
 }
 
 func (z *Zone) Add(flags *pflag.FlagSet, object string) {
-	z.value = flags.String("zone", "", "zone for the "+object)
+	z.value, z.flag = register(flags, "zone", "", "", "zone for the "+object)
 }
 
 func (a *Appliance) Required(flags *pflag.FlagSet) {
@@ -122,6 +358,14 @@ func (e *Environment) Required(flags *pflag.FlagSet) {
 	required(flags, "environment")
 }
 
+func (f *File) Required(flags *pflag.FlagSet) {
+	required(flags, "filename")
+}
+
+func (h *Host) Required(flags *pflag.FlagSet) {
+	required(flags, "host")
+}
+
 func (m *Make) Required(flags *pflag.FlagSet) {
 	required(flags, "make")
 }
@@ -134,6 +378,10 @@ func (r *Rack) Required(flags *pflag.FlagSet) {
 	required(flags, "rack")
 }
 
+func (t *Template) Required(flags *pflag.FlagSet) {
+	required(flags, "template")
+}
+
 func (z *Zone) Required(flags *pflag.FlagSet) {
 	required(flags, "zone")
 }
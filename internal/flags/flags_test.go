@@ -0,0 +1,80 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestStringPtr(t *testing.T) {
+	type ptrFlag interface {
+		Add(flags *pflag.FlagSet, object string)
+		Ptr() *string
+	}
+
+	flags := map[string]func() ptrFlag{
+		"rack":     func() ptrFlag { return new(Rack) },
+		"zone":     func() ptrFlag { return new(Zone) },
+		"host":     func() ptrFlag { return new(Host) },
+		"make":     func() ptrFlag { return new(Make) },
+		"arch":     func() ptrFlag { return new(Arch) },
+		"template": func() ptrFlag { return new(Template) },
+	}
+
+	for name, newFlag := range flags {
+		tests := []struct {
+			args []string
+			want *string
+		}{
+			{args: nil, want: nil},
+			{args: []string{"--" + name, "v1"}, want: ptr("v1")},
+			{args: []string{"--" + name, ""}, want: ptr("")},
+		}
+
+		for _, tt := range tests {
+			f := newFlag()
+
+			set := pflag.NewFlagSet("set", pflag.ContinueOnError)
+			f.Add(set, "host")
+
+			if err := set.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got := f.Ptr()
+
+			switch {
+			case got == nil && tt.want == nil:
+			case got == nil || tt.want == nil || *got != *tt.want:
+				t.Errorf("%q: got %v, want %v", tt.args, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestUint32Ptr(t *testing.T) {
+	var mtu MTU
+
+	set := pflag.NewFlagSet("set", pflag.ContinueOnError)
+	mtu.Add(set, "network")
+
+	if err := set.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mtu.Ptr(); got != nil {
+		t.Errorf("got %d without --mtu, want nil", *got)
+	}
+
+	if err := set.Parse([]string{"--mtu", "9000"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := mtu.Ptr(); got == nil || *got != 9000 {
+		t.Errorf("got %v with --mtu 9000, want 9000", got)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,636 @@
+// Package lint checks schema files against the schema message before they
+// are loaded, and reports each problem at its line and column in the file.
+//
+// Files are read as YAML, which covers JSON. Besides unknown fields, wrong
+// types and enum values, it finds objects named twice in a list and
+// references to objects that are not defined, like a host's rack. A field
+// is a reference when a list named after it, "racks" for "rack", is in an
+// enclosing object, or anywhere in another part of the schema for fields
+// like a host's model.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Include is the directive that pulls other files into a schema. Its value
+// is a path or list of paths, relative to the including file, and may be
+// globs. The included files are merged into the object holding it.
+const Include = "$include"
+
+// Problem is one finding. Line and Column are 0 when the file could not be
+// read at all.
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.File + ": " + p.Message
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// Linter collects the problems of one or more files that together make a
+// schema, so references may point into other files.
+type Linter struct {
+	desc     protoreflect.MessageDescriptor
+	problems []Problem
+	lists    map[string]map[string]bool // names by list id, like zones[east].racks
+	kinds    map[string]map[string]bool // names by list path, like makes.models
+	refs     []ref
+}
+
+// scope is the object being walked.
+type scope struct {
+	file    string
+	seen    []string // files being read, to catch include cycles
+	desc    protoreflect.MessageDescriptor
+	id      string   // with names, like zones[east].racks[r1]
+	path    string   // without names, like zones.racks
+	parents []string // ids of the enclosing objects, outermost first
+}
+
+// ref is a string field that may name an object.
+type ref struct {
+	at      Problem
+	field   string
+	value   string
+	path    string
+	parents []string
+}
+
+// New returns a Linter for schemas of the message desc.
+func New(desc protoreflect.MessageDescriptor) *Linter {
+	return &Linter{
+		desc:  desc,
+		lists: make(map[string]map[string]bool),
+		kinds: make(map[string]map[string]bool),
+	}
+}
+
+// Lint checks a file's contents and the files it includes. Name is used in
+// problems and to find included files.
+func (l *Linter) Lint(name string, data []byte) {
+	s := scope{file: name, desc: l.desc}
+
+	if abs, err := filepath.Abs(name); err == nil {
+		s.seen = []string{abs}
+	}
+
+	l.lint(data, s)
+}
+
+// Problems returns the problems found, ordered by file and line, after
+// resolving the references across every file linted.
+func (l *Linter) Problems() []Problem {
+	problems := slices.Clone(l.problems)
+
+	for _, r := range l.refs {
+		if msg := l.resolve(r); msg != "" {
+			at := r.at
+			at.Message = msg
+			problems = append(problems, at)
+		}
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+
+		return a.Column - b.Column
+	})
+
+	return problems
+}
+
+func (l *Linter) lint(data []byte, s scope) {
+	f, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		p := Problem{File: s.file, Message: yaml.FormatError(err, false, false)}
+
+		if _, err := fmt.Sscanf(p.Message, "[%d:%d]", &p.Line, &p.Column); err == nil {
+			_, p.Message, _ = strings.Cut(p.Message, "] ")
+		}
+
+		l.problems = append(l.problems, p)
+
+		return
+	}
+
+	for i, doc := range f.Docs {
+		if doc.Body == nil {
+			continue
+		}
+
+		if i > 0 {
+			l.report(s, doc.Body, "only the first document is read")
+
+			continue
+		}
+
+		l.message(doc.Body, s)
+	}
+}
+
+func (l *Linter) report(s scope, n ast.Node, format string, args ...any) {
+	l.problems = append(l.problems, at(s, n, fmt.Sprintf(format, args...)))
+}
+
+func at(s scope, n ast.Node, msg string) Problem {
+	p := Problem{File: s.file, Message: msg}
+
+	if tk := n.GetToken(); tk != nil && tk.Position != nil {
+		p.Line, p.Column = tk.Position.Line, tk.Position.Column
+	}
+
+	return p
+}
+
+// message checks an object against its message's fields.
+func (l *Linter) message(n ast.Node, s scope) {
+	n = unwrap(n)
+	if isNull(n) {
+		return
+	}
+
+	pairs, ok := mapping(n)
+	if !ok {
+		l.report(s, n, "expected an object for %s", s.desc.Name())
+
+		return
+	}
+
+	first := make(map[string]int)
+
+	for _, kv := range pairs {
+		if kv.Key.IsMergeKey() {
+			continue
+		}
+
+		key := text(kv.Key)
+
+		if line, dup := first[key]; dup {
+			l.report(s, kv.Key, "duplicate field %q, first at line %d", key, line)
+
+			continue
+		}
+
+		first[key] = kv.Key.GetToken().Position.Line
+
+		if key == Include {
+			l.include(kv.Value, s)
+
+			continue
+		}
+
+		fd := s.desc.Fields().ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = s.desc.Fields().ByJSONName(key)
+		}
+
+		if fd == nil {
+			l.report(s, kv.Key, "unknown field %q in %s", key, s.desc.Name())
+
+			continue
+		}
+
+		l.field(kv.Value, fd, s)
+	}
+}
+
+func (l *Linter) field(n ast.Node, fd protoreflect.FieldDescriptor, s scope) {
+	n = unwrap(n)
+	if isNull(n) {
+		return
+	}
+
+	switch {
+	case fd.IsList():
+		seq, ok := n.(*ast.SequenceNode)
+		if !ok {
+			l.report(s, n, "expected a list for %s", fd.Name())
+
+			return
+		}
+
+		if fd.Kind() == protoreflect.MessageKind {
+			l.list(seq, fd, s)
+
+			return
+		}
+
+		for _, e := range seq.Values {
+			l.scalar(e, fd, s)
+		}
+
+	case fd.IsMap():
+		pairs, ok := mapping(n)
+		if !ok {
+			l.report(s, n, "expected an object for %s", fd.Name())
+
+			return
+		}
+
+		for _, kv := range pairs {
+			l.value(kv.Value, fd.MapValue(), s)
+		}
+
+	default:
+		l.value(n, fd, s)
+	}
+}
+
+// list checks a list of objects, which must have unique names when they
+// have a name field.
+func (l *Linter) list(seq *ast.SequenceNode, fd protoreflect.FieldDescriptor, s scope) {
+	field := string(fd.Name())
+	listID := join(s.id, field)
+	path := join(s.path, field)
+	hasName := fd.Message().Fields().ByName("name") != nil
+
+	if hasName {
+		if l.lists[listID] == nil {
+			l.lists[listID] = make(map[string]bool)
+		}
+
+		if l.kinds[path] == nil {
+			l.kinds[path] = make(map[string]bool)
+		}
+	}
+
+	first := make(map[string]int)
+
+	for i, e := range seq.Values {
+		e = unwrap(e)
+
+		sub := scope{
+			file:    s.file,
+			seen:    s.seen,
+			desc:    fd.Message(),
+			id:      fmt.Sprintf("%s[%d]", listID, i),
+			path:    path,
+			parents: append(slices.Clip(s.parents), s.id),
+		}
+
+		if name, node := nameOf(e); hasName && node != nil {
+			if line, dup := first[name]; dup {
+				l.report(s, node, "duplicate %s %q, first at line %d", singular(field), name, line)
+			} else {
+				first[name] = node.GetToken().Position.Line
+			}
+
+			sub.id = listID + "[" + name + "]"
+			l.lists[listID][name] = true
+			l.kinds[path][name] = true
+		}
+
+		l.message(e, sub)
+	}
+}
+
+// value checks a single value of a field.
+func (l *Linter) value(n ast.Node, fd protoreflect.FieldDescriptor, s scope) {
+	n = unwrap(n)
+	if isNull(n) {
+		return
+	}
+
+	if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+		l.scalar(n, fd, s)
+
+		return
+	}
+
+	// Well known types, like timestamps, have their own JSON forms.
+	if strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+		return
+	}
+
+	l.message(n, scope{
+		file:    s.file,
+		seen:    s.seen,
+		desc:    fd.Message(),
+		id:      join(s.id, string(fd.Name())),
+		path:    join(s.path, string(fd.Name())),
+		parents: append(slices.Clip(s.parents), s.id),
+	})
+}
+
+func (l *Linter) scalar(n ast.Node, fd protoreflect.FieldDescriptor, s scope) {
+	n = unwrap(n)
+	if isNull(n) {
+		return
+	}
+
+	var ok bool
+
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		ok = isString(n)
+		if ok && fd.Kind() == protoreflect.StringKind && !fd.IsList() && fd.Name() != "name" {
+			l.refs = append(l.refs, ref{
+				at:      at(s, n, ""),
+				field:   string(fd.Name()),
+				value:   text(n),
+				path:    s.path,
+				parents: append(slices.Clip(s.parents), s.id),
+			})
+		}
+
+	case protoreflect.BoolKind:
+		_, ok = n.(*ast.BoolNode)
+
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+
+		switch n := n.(type) {
+		case *ast.IntegerNode:
+			i, err := strconv.ParseInt(text(n), 10, 32)
+			if err == nil && values.ByNumber(protoreflect.EnumNumber(i)) != nil {
+				return
+			}
+		case *ast.StringNode:
+			if values.ByName(protoreflect.Name(n.Value)) != nil {
+				return
+			}
+		}
+
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+
+		l.report(s, n, "invalid %s %q, want one of %s", fd.Name(), text(n), strings.Join(names, ", "))
+
+		return
+
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		switch n.(type) {
+		case *ast.FloatNode, *ast.IntegerNode, *ast.InfinityNode, *ast.NanNode:
+			ok = true
+		}
+
+	default: // integers, which may be quoted as in JSON
+		switch n.(type) {
+		case *ast.IntegerNode, *ast.StringNode:
+			ok = isInteger(text(n), fd.Kind())
+		}
+	}
+
+	if !ok {
+		l.report(s, n, "expected %s for %s, got %s", kindName(fd.Kind()), fd.Name(), text(n))
+	}
+}
+
+// include lints the files an include directive names as part of the object
+// holding it.
+func (l *Linter) include(n ast.Node, s scope) {
+	n = unwrap(n)
+
+	var paths []ast.Node
+
+	switch v := n.(type) {
+	case *ast.SequenceNode:
+		paths = v.Values
+	default:
+		paths = []ast.Node{n}
+	}
+
+	for _, p := range paths {
+		p = unwrap(p)
+		if !isString(p) {
+			l.report(s, p, "expected a path for %s", Include)
+
+			continue
+		}
+
+		name := text(p)
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(s.file), name)
+		}
+
+		matches, err := filepath.Glob(name)
+		if err != nil {
+			l.report(s, p, "%s: %v", Include, err)
+
+			continue
+		}
+
+		if len(matches) == 0 {
+			l.report(s, p, "no files match %s", name)
+
+			continue
+		}
+
+		for _, m := range matches {
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				l.report(s, p, "%v", err)
+
+				continue
+			}
+
+			if slices.Contains(s.seen, abs) {
+				l.report(s, p, "include cycle through %s", m)
+
+				continue
+			}
+
+			data, err := os.ReadFile(m)
+			if err != nil {
+				l.report(s, p, "%v", err)
+
+				continue
+			}
+
+			sub := s
+			sub.file = m
+			sub.seen = append(slices.Clip(s.seen), abs)
+
+			l.lint(data, sub)
+		}
+	}
+}
+
+// resolve returns why a reference is dangling, or "" if it is not one.
+func (l *Linter) resolve(r ref) string {
+	field := plural(r.field)
+
+	for i := len(r.parents) - 1; i >= 0; i-- {
+		id := join(r.parents[i], field)
+		if names, ok := l.lists[id]; ok {
+			if names[r.value] {
+				return ""
+			}
+
+			return fmt.Sprintf("%s %q not found in %s", r.field, r.value, id)
+		}
+	}
+
+	// Lists elsewhere in the schema, like makes.models for a host's model,
+	// but not the same list of a sibling, like another zone's racks.
+	top, _, _ := strings.Cut(r.path, ".")
+
+	var found []string
+
+	for path, names := range l.kinds {
+		if path != field && !strings.HasSuffix(path, "."+field) {
+			continue
+		}
+
+		if t, _, _ := strings.Cut(path, "."); t == top {
+			continue
+		}
+
+		if names[r.value] {
+			return ""
+		}
+
+		found = append(found, path)
+	}
+
+	if len(found) == 0 {
+		return ""
+	}
+
+	slices.Sort(found)
+
+	return fmt.Sprintf("%s %q not found in %s", r.field, r.value, strings.Join(found, ", "))
+}
+
+// unwrap returns the value under anchors and tags.
+func unwrap(n ast.Node) ast.Node {
+	for {
+		switch v := n.(type) {
+		case *ast.AnchorNode:
+			n = v.Value
+		case *ast.TagNode:
+			n = v.Value
+		default:
+			return n
+		}
+	}
+}
+
+func isNull(n ast.Node) bool {
+	_, ok := n.(*ast.NullNode)
+
+	return n == nil || ok
+}
+
+func isString(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.StringNode, *ast.LiteralNode:
+		return true
+	}
+
+	return false
+}
+
+func mapping(n ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch v := n.(type) {
+	case *ast.MappingNode:
+		return v.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{v}, true
+	}
+
+	return nil, false
+}
+
+// nameOf returns an object's name and the node holding it.
+func nameOf(n ast.Node) (string, ast.Node) {
+	pairs, _ := mapping(n)
+
+	for _, kv := range pairs {
+		if text(kv.Key) == "name" {
+			if v := unwrap(kv.Value); isString(v) {
+				return text(v), v
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// text is a scalar's value, or the node as YAML.
+func text(n ast.Node) string {
+	switch v := n.(type) {
+	case *ast.StringNode:
+		return v.Value
+	case *ast.LiteralNode:
+		return v.Value.Value
+	case ast.ScalarNode:
+		return fmt.Sprint(v.GetValue())
+	}
+
+	return n.String()
+}
+
+// isInteger reports whether s is a decimal integer in the range of an
+// integer kind.
+func isInteger(s string, k protoreflect.Kind) bool {
+	var err error
+
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		_, err = strconv.ParseInt(s, 10, 32)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		_, err = strconv.ParseUint(s, 10, 32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		_, err = strconv.ParseUint(s, 10, 64)
+	default:
+		_, err = strconv.ParseInt(s, 10, 64)
+	}
+
+	return err == nil
+}
+
+func kindName(k protoreflect.Kind) string {
+	switch k {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "a string"
+	case protoreflect.BoolKind:
+		return "true or false"
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return "a number"
+	}
+
+	return "an integer"
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func plural(s string) string {
+	return s + "s"
+}
+
+func singular(s string) string {
+	return strings.TrimSuffix(s, "s")
+}
@@ -0,0 +1,272 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// schema is a small schema in the shape of the metal one: zones with racks
+// and hosts, and makes with models.
+func schema(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		if typeName != "" {
+			f.TypeName = proto.String(".test." + typeName)
+		}
+
+		if repeated {
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		}
+
+		return f
+	}
+
+	const (
+		str    = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		msg    = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		enum   = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		int32_ = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		uint32 = descriptorpb.FieldDescriptorProto_TYPE_UINT32
+		int64_ = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	)
+
+	message := func(name string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Architecture"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("ARCHITECTURE_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("ARCHITECTURE_X86_64"), Number: proto.Int32(1)},
+				{Name: proto.String("ARCHITECTURE_AARCH64"), Number: proto.Int32(2)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("Schema",
+				field("zones", 1, msg, "Zone", true),
+				field("makes", 2, msg, "Make", true)),
+			message("Zone",
+				field("name", 1, str, "", false),
+				field("racks", 2, msg, "Rack", true),
+				field("hosts", 3, msg, "Host", true)),
+			message("Rack",
+				field("name", 1, str, "", false)),
+			message("Host",
+				field("name", 1, str, "", false),
+				field("rack", 2, str, "", false),
+				field("model", 3, str, "", false),
+				field("vlan", 4, uint32, "", false),
+				field("rank", 5, int32_, "", false),
+				field("serial", 6, int64_, "", false)),
+			message("Make",
+				field("name", 1, str, "", false),
+				field("models", 2, msg, "Model", true)),
+			message("Model",
+				field("name", 1, str, "", false),
+				field("arch", 2, enum, "Architecture", false)),
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fd.Messages().ByName("Schema")
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string // by name, the first linted is schema.yaml
+		want  []string
+	}{
+		{
+			name: "valid",
+			files: map[string]string{"schema.yaml": `
+makes:
+- name: dell
+  models:
+  - name: r640
+    arch: ARCHITECTURE_X86_64
+  - name: r740
+    arch: 2
+zones:
+- name: east
+  racks:
+  - name: r1
+  hosts:
+  - name: h1
+    rack: r1
+    model: r640
+    vlan: 10
+    rank: "-3"
+    serial: "12345678901"
+`},
+		},
+		{
+			name: "unknown field",
+			files: map[string]string{"schema.yaml": `
+zones:
+- name: east
+  rackz: []
+`},
+			want: []string{`schema.yaml:4:3: unknown field "rackz" in Zone`},
+		},
+		{
+			name: "bad enum",
+			files: map[string]string{"schema.yaml": `
+makes:
+- name: dell
+  models:
+  - name: r640
+    arch: SPARC
+  - name: r740
+    arch: 7
+`},
+			want: []string{
+				`schema.yaml:6:11: invalid arch "SPARC", want one of ARCHITECTURE_UNSPECIFIED, ARCHITECTURE_X86_64, ARCHITECTURE_AARCH64`,
+				`schema.yaml:8:11: invalid arch "7", want one of ARCHITECTURE_UNSPECIFIED, ARCHITECTURE_X86_64, ARCHITECTURE_AARCH64`,
+			},
+		},
+		{
+			name: "bad integers",
+			files: map[string]string{"schema.yaml": `
+zones:
+- name: east
+  hosts:
+  - name: h1
+    vlan: -1
+    rank: "3.5"
+    serial: x
+`},
+			want: []string{
+				`schema.yaml:6:11: expected an integer for vlan, got -1`,
+				`schema.yaml:7:11: expected an integer for rank, got 3.5`,
+				`schema.yaml:8:13: expected an integer for serial, got x`,
+			},
+		},
+		{
+			name: "duplicate name",
+			files: map[string]string{"schema.yaml": `
+zones:
+- name: east
+  racks:
+  - name: r1
+  - name: r1
+`},
+			want: []string{`schema.yaml:6:11: duplicate rack "r1", first at line 5`},
+		},
+		{
+			name: "dangling ref",
+			files: map[string]string{"schema.yaml": `
+makes:
+- name: dell
+  models:
+  - name: r640
+zones:
+- name: east
+  racks:
+  - name: r1
+  hosts:
+  - name: h1
+    rack: r2
+    model: r650
+`},
+			want: []string{
+				`schema.yaml:12:11: rack "r2" not found in zones[east].racks`,
+				`schema.yaml:13:12: model "r650" not found in makes.models`,
+			},
+		},
+		{
+			name: "ref in an include",
+			files: map[string]string{
+				"schema.yaml": `
+zones:
+- name: east
+  $include: east.yaml
+`,
+				"east.yaml": `
+racks:
+- name: r1
+hosts:
+- name: h1
+  rack: r1
+- name: h2
+  rack: r9
+`,
+			},
+			want: []string{`east.yaml:8:9: rack "r9" not found in zones[east].racks`},
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"schema.yaml": `
+zones:
+- name: east
+  $include: east.yaml
+`,
+				"east.yaml": `
+$include: schema.yaml
+`,
+			},
+			want: []string{
+				`east.yaml:2:11: include cycle through schema.yaml`,
+			},
+		},
+	}
+
+	desc := schema(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			t.Chdir(dir)
+
+			data, err := os.ReadFile("schema.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			l := New(desc)
+			l.Lint("schema.yaml", data)
+
+			var got []string
+			for _, p := range l.Problems() {
+				got = append(got, p.String())
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
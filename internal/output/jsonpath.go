@@ -0,0 +1,648 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a kubectl style JSONPath template: text with {} expressions,
+// {range EXPR}...{end} loops and {"quoted"} literals. Expressions support
+// .field, ['field'], .*, [*], ..field, [n], [start:end] and filters like
+// [?(@.zone=="a")].
+type jsonPath struct {
+	nodes []pathNode
+}
+
+type pathNode struct {
+	text string     // literal text
+	expr *pathExpr  // expression to print, or to range over
+	body []pathNode // body of a range
+}
+
+type pathExpr struct {
+	root  bool // starts at $, not the current object
+	steps []pathStep
+}
+
+type pathStep struct {
+	kind   stepKind
+	name   string
+	index  int
+	end    *int
+	filter *pathFilter
+}
+
+type stepKind int
+
+const (
+	stepField stepKind = iota
+	stepWildcard
+	stepRecursive // name is "" for ..*
+	stepIndex
+	stepSlice
+	stepFilter
+)
+
+type pathFilter struct {
+	left  *pathExpr
+	op    string // "" tests that left exists
+	right any
+}
+
+func parseJSONPath(s string) (*jsonPath, error) {
+	nodes, rest, err := parseNodes(s, false)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath: %w", err)
+	}
+
+	if rest != "" {
+		return nil, errors.New("jsonpath: {end} without {range}")
+	}
+
+	return &jsonPath{nodes: nodes}, nil
+}
+
+// parseNodes parses until the end of s, or the {end} closing a range when
+// inRange is set, and returns what is left after it.
+func parseNodes(s string, inRange bool) ([]pathNode, string, error) {
+	var nodes []pathNode
+
+	for s != "" {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			nodes = append(nodes, pathNode{text: s})
+			s = ""
+
+			break
+		}
+
+		if open > 0 {
+			nodes = append(nodes, pathNode{text: s[:open]})
+		}
+
+		n := closing(s[open:])
+		if n < 0 {
+			return nil, "", fmt.Errorf("unclosed %q", s[open:])
+		}
+
+		action := strings.TrimSpace(s[open+1 : open+n])
+		s = s[open+n+1:]
+
+		switch {
+		case action == "end":
+			if !inRange {
+				return nodes, "{end}", nil
+			}
+
+			return nodes, s, nil
+
+		case strings.HasPrefix(action, "range "):
+			expr, err := parseExpr(strings.TrimSpace(strings.TrimPrefix(action, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+
+			body, rest, err := parseNodes(s, true)
+			if err != nil {
+				return nil, "", err
+			}
+
+			nodes = append(nodes, pathNode{expr: expr, body: body})
+			s = rest
+
+			continue
+
+		case strings.HasPrefix(action, `"`):
+			text, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid literal %s", action)
+			}
+
+			nodes = append(nodes, pathNode{text: text})
+
+		default:
+			expr, err := parseExpr(action)
+			if err != nil {
+				return nil, "", err
+			}
+
+			nodes = append(nodes, pathNode{expr: expr})
+		}
+	}
+
+	if inRange {
+		return nil, "", errors.New("{range} without {end}")
+	}
+
+	return nodes, "", nil
+}
+
+// closing returns the index of the brace closing s[0], skipping quoted text.
+func closing(s string) int {
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			j := strings.IndexByte(s[i+1:], s[i])
+			if j < 0 {
+				return -1
+			}
+
+			i += j + 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func parseExpr(s string) (*pathExpr, error) {
+	var expr pathExpr
+
+	orig := s
+
+	switch {
+	case strings.HasPrefix(s, "$"):
+		expr.root = true
+		s = s[1:]
+	case strings.HasPrefix(s, "@"):
+		s = s[1:]
+	}
+
+	for s != "" {
+		var step pathStep
+
+		switch {
+		case strings.HasPrefix(s, ".."):
+			s = s[2:]
+			name := fieldName(s)
+			s = s[len(name):]
+
+			step = pathStep{kind: stepRecursive, name: strings.TrimPrefix(name, "*")}
+
+		case strings.HasPrefix(s, "."):
+			s = s[1:]
+			name := fieldName(s)
+			s = s[len(name):]
+
+			switch name {
+			case "":
+				continue // "." alone is the current object
+			case "*":
+				step = pathStep{kind: stepWildcard}
+			default:
+				step = pathStep{kind: stepField, name: name}
+			}
+
+		case strings.HasPrefix(s, "["):
+			n := bracket(s)
+			if n < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", orig)
+			}
+
+			var err error
+
+			step, err = parseBracket(strings.TrimSpace(s[1:n]))
+			if err != nil {
+				return nil, fmt.Errorf("%w in %q", err, orig)
+			}
+
+			s = s[n+1:]
+
+		default:
+			name := fieldName(s)
+			if name == "" {
+				return nil, fmt.Errorf("unexpected %q in %q", s, orig)
+			}
+
+			s = s[len(name):]
+			step = pathStep{kind: stepField, name: name}
+		}
+
+		expr.steps = append(expr.steps, step)
+	}
+
+	return &expr, nil
+}
+
+func fieldName(s string) string {
+	if i := strings.IndexAny(s, ".[ =!<>)"); i >= 0 {
+		return s[:i]
+	}
+
+	return s
+}
+
+// bracket returns the index of the ] closing s[0].
+func bracket(s string) int {
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			j := strings.IndexByte(s[i+1:], s[i])
+			if j < 0 {
+				return -1
+			}
+
+			i += j + 1
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func parseBracket(s string) (pathStep, error) {
+	switch {
+	case s == "*":
+		return pathStep{kind: stepWildcard}, nil
+
+	case strings.HasPrefix(s, "?(") && strings.HasSuffix(s, ")"):
+		f, err := parseFilter(strings.TrimSpace(s[2 : len(s)-1]))
+		if err != nil {
+			return pathStep{}, err
+		}
+
+		return pathStep{kind: stepFilter, filter: f}, nil
+
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, `"`):
+		name, err := unquote(s)
+		if err != nil {
+			return pathStep{}, err
+		}
+
+		return pathStep{kind: stepField, name: name}, nil
+
+	case strings.Contains(s, ":"):
+		start, end, _ := strings.Cut(s, ":")
+		step := pathStep{kind: stepSlice}
+
+		if start = strings.TrimSpace(start); start != "" {
+			i, err := strconv.Atoi(start)
+			if err != nil {
+				return pathStep{}, fmt.Errorf("invalid slice [%s]", s)
+			}
+
+			step.index = i
+		}
+
+		if end = strings.TrimSpace(end); end != "" {
+			i, err := strconv.Atoi(end)
+			if err != nil {
+				return pathStep{}, fmt.Errorf("invalid slice [%s]", s)
+			}
+
+			step.end = &i
+		}
+
+		return step, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return pathStep{}, fmt.Errorf("invalid index [%s]", s)
+	}
+
+	return pathStep{kind: stepIndex, index: i}, nil
+}
+
+func parseFilter(s string) (*pathFilter, error) {
+	var f pathFilter
+
+	left := s
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if l, r, ok := strings.Cut(s, op); ok {
+			left, f.op = strings.TrimSpace(l), op
+
+			right := strings.TrimSpace(r)
+
+			switch {
+			case strings.HasPrefix(right, "'") || strings.HasPrefix(right, `"`):
+				v, err := unquote(right)
+				if err != nil {
+					return nil, err
+				}
+
+				f.right = v
+			case right == "true" || right == "false":
+				f.right = right == "true"
+			default:
+				v, err := strconv.ParseFloat(right, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid filter value %s", right)
+				}
+
+				f.right = v
+			}
+
+			break
+		}
+	}
+
+	expr, err := parseExpr(left)
+	if err != nil {
+		return nil, err
+	}
+
+	f.left = expr
+
+	return &f, nil
+}
+
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) > 1 {
+		return s[1 : len(s)-1], nil
+	}
+
+	return strconv.Unquote(s)
+}
+
+func (p *jsonPath) execute(w io.Writer, data any) error {
+	return executeNodes(w, p.nodes, data, data)
+}
+
+func executeNodes(w io.Writer, nodes []pathNode, root, cur any) error {
+	for _, n := range nodes {
+		if n.expr == nil {
+			if _, err := io.WriteString(w, n.text); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		values := n.expr.eval(root, cur)
+
+		if n.body != nil {
+			if len(values) == 1 {
+				if s, ok := values[0].([]any); ok {
+					values = s
+				}
+			}
+
+			for _, v := range values {
+				if err := executeNodes(w, n.body, root, v); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		s := make([]string, len(values))
+		for i, v := range values {
+			s[i] = pathText(v)
+		}
+
+		if _, err := io.WriteString(w, strings.Join(s, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *pathExpr) eval(root, cur any) []any {
+	values := []any{cur}
+	if e.root {
+		values = []any{root}
+	}
+
+	for _, step := range e.steps {
+		var next []any
+
+		for _, v := range values {
+			next = append(next, step.apply(root, v)...)
+		}
+
+		values = next
+	}
+
+	return values
+}
+
+func (s *pathStep) apply(root, v any) []any {
+	switch s.kind {
+	case stepField:
+		if m, ok := v.(map[string]any); ok {
+			if f, ok := m[s.name]; ok {
+				return []any{f}
+			}
+		}
+
+	case stepWildcard:
+		return children(v)
+
+	case stepRecursive:
+		var out []any
+
+		for _, d := range descendants(v) {
+			if s.name == "" {
+				out = append(out, children(d)...)
+			} else if m, ok := d.(map[string]any); ok {
+				if f, ok := m[s.name]; ok {
+					out = append(out, f)
+				}
+			}
+		}
+
+		return out
+
+	case stepIndex:
+		if l, ok := v.([]any); ok {
+			i := s.index
+			if i < 0 {
+				i += len(l)
+			}
+
+			if i >= 0 && i < len(l) {
+				return []any{l[i]}
+			}
+		}
+
+	case stepSlice:
+		if l, ok := v.([]any); ok {
+			start, end := s.index, len(l)
+			if s.end != nil {
+				end = *s.end
+			}
+
+			if start < 0 {
+				start += len(l)
+			}
+
+			if end < 0 {
+				end += len(l)
+			}
+
+			start, end = max(0, start), min(len(l), end)
+			if start < end {
+				return l[start:end]
+			}
+		}
+
+	case stepFilter:
+		var out []any
+
+		for _, c := range children(v) {
+			if s.filter.match(root, c) {
+				out = append(out, c)
+			}
+		}
+
+		return out
+	}
+
+	return nil
+}
+
+func (f *pathFilter) match(root, v any) bool {
+	values := f.left.eval(root, v)
+	if f.op == "" {
+		return len(values) > 0
+	}
+
+	for _, l := range values {
+		c, ok := compare(l, f.right)
+		if !ok {
+			if f.op == "!=" {
+				return true
+			}
+
+			continue
+		}
+
+		switch f.op {
+		case "==":
+			ok = c == 0
+		case "!=":
+			ok = c != 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compare compares numbers as numbers and everything else as text. The
+// result is false if a number is compared to a string that is not one.
+func compare(a, b any) (int, bool) {
+	if n, ok := b.(float64); ok {
+		m, ok := number(a)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case m < n:
+			return -1, true
+		case m > n:
+			return 1, true
+		}
+
+		return 0, true
+	}
+
+	return strings.Compare(pathText(a), pathText(b)), true
+}
+
+func number(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), true
+	case rv.CanUint():
+		return float64(rv.Uint()), true
+	case rv.CanFloat():
+		return rv.Float(), true
+	case rv.Kind() == reflect.String:
+		f, err := strconv.ParseFloat(rv.String(), 64)
+
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// children returns the elements of a list, or the values of an object in
+// key order.
+func children(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		var out []any
+
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			out = append(out, v[k])
+		}
+
+		return out
+	}
+
+	return nil
+}
+
+func descendants(v any) []any {
+	out := []any{v}
+
+	for _, c := range children(v) {
+		out = append(out, descendants(c)...)
+	}
+
+	return out
+}
+
+// pathText prints a value the way kubectl does: lists and objects as JSON,
+// everything else as text.
+func pathText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any, map[string]any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(b)
+	}
+
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const jsonPathData = `{
+	"items": [
+		{"name": "r1", "zone": "east", "size": 10, "attrs": {"power": "a", "row": "1"}},
+		{"name": "r2", "zone": "west", "size": 20, "attrs": {"power": "b"}},
+		{"name": "r3", "zone": "east", "size": 30}
+	]
+}`
+
+func TestJSONPath(t *testing.T) {
+	var data any
+
+	if err := json.Unmarshal([]byte(jsonPathData), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		template string
+		want     string
+	}{
+		// Fields and literals.
+		{`{.items[0].name}`, "r1"},
+		{`{$.items[0].zone}`, "east"},
+		{`{.items[0]['name']}`, "r1"},
+		{`{.items[0].attrs}`, `{"power":"a","row":"1"}`},
+		{`{.items[0].missing}`, ""},
+		{`name: {.items[1].name}{"\n"}`, "name: r2\n"},
+
+		// Wildcards.
+		{`{.items[*].name}`, "r1 r2 r3"},
+		{`{.items[0].attrs.*}`, "a 1"},
+
+		// Indexes and slices.
+		{`{.items[-1].name}`, "r3"},
+		{`{.items[5].name}`, ""},
+		{`{.items[0:2].name}`, "r1 r2"},
+		{`{.items[1:].name}`, "r2 r3"},
+		{`{.items[:-1].name}`, "r1 r2"},
+		{`{.items[-2:].name}`, "r2 r3"},
+		{`{.items[2:1].name}`, ""},
+
+		// Filters.
+		{`{.items[?(@.zone=="east")].name}`, "r1 r3"},
+		{`{.items[?(@.zone!="east")].name}`, "r2"},
+		{`{.items[?(@.size>15)].name}`, "r2 r3"},
+		{`{.items[?(@.size<=20)].name}`, "r1 r2"},
+		{`{.items[?(@.attrs.power)].name}`, "r1 r2"},
+		{`{.items[?(@.attrs.power=="b")].zone}`, "west"},
+
+		// Recursive descent.
+		{`{..power}`, "a b"},
+		{`{.items[0]..*}`, `{"power":"a","row":"1"} r1 10 east a 1`},
+
+		// Ranges.
+		{`{range .items[*]}{.name}={.zone}{"\n"}{end}`, "r1=east\nr2=west\nr3=east\n"},
+		{`{range .items[?(@.size>=20)]}[{.name}]{end}`, "[r2][r3]"},
+		{`{range .items}{.name},{end}`, "r1,r2,r3,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			p, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var b strings.Builder
+
+			if err := p.execute(&b, data); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	for _, template := range []string{
+		`{.items`,
+		`{range .items}{.name}`,
+		`{.name}{end}`,
+		`{.items[}`,
+		`{.items[?(@.zone=="east"]}`,
+	} {
+		if _, err := parseJSONPath(template); err == nil {
+			t.Errorf("%s: parsed, want an error", template)
+		}
+	}
+}
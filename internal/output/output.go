@@ -0,0 +1,518 @@
+// Package output writes the rows of list commands as a table or in a
+// machine readable format.
+package output
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"endobit.io/table"
+)
+
+// Writer writes rows, structs with one field per column, as a table, wide
+// table, json, yaml, csv, tsv, names, or through a JSONPath or Go template
+// given inline or as a file. Fields tagged `output:"wide"` are left out of
+// the table format.
+//
+// Keys in json, yaml and csv output are the snake_case field names. The name
+// format prints the column named after the object, one per line. Templates
+// are executed once over {"items": [...]}, the server's responses in their
+// protojson form, so fields that are not columns can be reached too.
+//
+// Rows are held until Flush so they can be sorted and limited.
+type Writer struct {
+	w         io.Writer
+	format    string
+	object    string
+	opts      Options
+	selectors []selector
+	table     *table.Table
+	csv       *csv.Writer
+	jsonPath  *jsonPath
+	template  *template.Template
+	header    bool
+	pending   []pending
+	rows      []any
+	types     map[reflect.Type]reflect.Type
+	err       error
+}
+
+// Options select, order and limit the rows and columns written.
+type Options struct {
+	Columns  []string // columns by field name, for example "Zone,TimeZone"
+	SortBy   string   // column to sort by
+	Reverse  bool
+	Limit    int      // maximum number of rows, 0 for all
+	Where    []string // see parseWhere
+	Selector []string // see parseSelector
+
+	// Attrs returns the attrs of the object a row describes, the row given
+	// as column keys to text. It is called only for attr selectors.
+	Attrs func(row map[string]string) (map[string]string, error)
+}
+
+// New returns a Writer for format.
+func New(w io.Writer, format, object string, opts Options) (*Writer, error) {
+	o := Writer{
+		w:      w,
+		object: object,
+		opts:   opts,
+		types:  make(map[reflect.Type]reflect.Type),
+	}
+
+	for _, s := range opts.Where {
+		sels, err := parseWhere(s)
+		if err != nil {
+			return nil, err
+		}
+
+		o.selectors = append(o.selectors, sels...)
+	}
+
+	for _, s := range opts.Selector {
+		sels, err := parseSelector(s)
+		if err != nil {
+			return nil, err
+		}
+
+		o.selectors = append(o.selectors, sels...)
+	}
+
+	spec := format
+	format, arg, hasArg := strings.Cut(spec, "=")
+
+	if strings.HasSuffix(format, "-file") && hasArg {
+		b, err := os.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		format, arg = strings.TrimSuffix(format, "-file"), string(b)
+	}
+
+	o.format = format
+
+	switch format {
+	case "", "table", "wide":
+		o.table = table.New()
+	case "csv", "tsv":
+		o.csv = csv.NewWriter(w)
+		if format == "tsv" {
+			o.csv.Comma = '\t'
+		}
+	case "json", "yaml", "name":
+	case "jsonpath":
+		p, err := parseJSONPath(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		o.jsonPath = p
+	case "go-template":
+		t, err := template.New("output").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		o.template = t
+	default:
+		return nil, fmt.Errorf("unknown output format %q", spec)
+	}
+
+	if hasArg != (o.jsonPath != nil || o.template != nil) {
+		return nil, fmt.Errorf("invalid output format %q", spec)
+	}
+
+	return &o, nil
+}
+
+// pending is a row held until Flush, and the response it was made from.
+type pending struct {
+	row  reflect.Value
+	resp proto.Message
+}
+
+// Write adds a row, the columns of the response resp, if it matches the
+// selectors. Once a write fails the error is kept and returned again by
+// Flush.
+func (o *Writer) Write(row any, resp proto.Message) error {
+	if o.err != nil {
+		return o.err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(row))
+
+	ok, err := o.match(v)
+	if err != nil {
+		o.err = err
+
+		return err
+	}
+
+	if ok {
+		o.pending = append(o.pending, pending{row: v, resp: resp})
+	}
+
+	return nil
+}
+
+func (o *Writer) match(v reflect.Value) (bool, error) {
+	if len(o.selectors) == 0 {
+		return true, nil
+	}
+
+	row := make(map[string]string, v.NumField())
+	for i, k := range keys(v.Type()) {
+		row[k] = text(v.Field(i))
+	}
+
+	var attrs map[string]string
+
+	for _, sel := range o.selectors {
+		var (
+			value string
+			found bool
+		)
+
+		if name, ok := strings.CutPrefix(sel.key, "attr."); ok {
+			if attrs == nil {
+				if o.opts.Attrs == nil {
+					return false, fmt.Errorf("%ss have no attrs to select on", o.object)
+				}
+
+				a, err := o.opts.Attrs(row)
+				if err != nil {
+					return false, err
+				}
+
+				attrs = a
+				if attrs == nil {
+					attrs = map[string]string{}
+				}
+			}
+
+			value, found = attrs[name]
+		} else {
+			i, err := column(v.Type(), sel.key)
+			if err != nil {
+				return false, err
+			}
+
+			value, found = text(v.Field(i)), true
+		}
+
+		if !sel.match(value, found) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (o *Writer) write(p pending) error {
+	v := p.row
+
+	switch o.format {
+	case "", "table", "wide":
+		out, err := o.convert(v)
+		if err != nil {
+			return err
+		}
+
+		return o.table.Write(out.Interface())
+
+	case "csv", "tsv":
+		out, err := o.convert(v)
+		if err != nil {
+			return err
+		}
+
+		if !o.header {
+			o.header = true
+
+			if err := o.csv.Write(keys(out.Type())); err != nil {
+				return err
+			}
+		}
+
+		record := make([]string, out.NumField())
+		for i := range record {
+			record[i] = text(out.Field(i))
+		}
+
+		return o.csv.Write(record)
+
+	case "json", "yaml":
+		out, err := o.convert(v)
+		if err != nil {
+			return err
+		}
+
+		o.rows = append(o.rows, out.Interface())
+
+	case "jsonpath", "go-template":
+		b, err := protojson.MarshalOptions{
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		}.Marshal(p.resp)
+		if err != nil {
+			return err
+		}
+
+		var item any
+
+		if err := json.Unmarshal(b, &item); err != nil {
+			return err
+		}
+
+		o.rows = append(o.rows, item)
+
+	case "name":
+		i := slices.Index(keys(v.Type()), o.object)
+		if i < 0 {
+			return fmt.Errorf("%ss have no %s column to output by name", o.object, o.object)
+		}
+
+		_, err := fmt.Fprintln(o.w, text(v.Field(i)))
+
+		return err
+	}
+
+	return nil
+}
+
+// Flush writes any buffered rows and returns the first error seen. Json and
+// yaml are written as a single list, an empty one if there were no rows.
+func (o *Writer) Flush() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if err := o.sort(); err != nil {
+		return err
+	}
+
+	rows := o.pending
+	if o.opts.Limit > 0 && len(rows) > o.opts.Limit {
+		rows = rows[:o.opts.Limit]
+	}
+
+	for _, p := range rows {
+		if err := o.write(p); err != nil {
+			return err
+		}
+	}
+
+	if o.rows == nil {
+		o.rows = []any{}
+	}
+
+	switch o.format {
+	case "", "table", "wide":
+		o.table.Flush()
+
+	case "csv", "tsv":
+		o.csv.Flush()
+
+		return o.csv.Error()
+
+	case "json":
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(o.rows)
+
+	case "yaml":
+		b, err := yaml.Marshal(o.rows)
+		if err != nil {
+			return err
+		}
+
+		_, err = o.w.Write(b)
+
+		return err
+
+	case "jsonpath":
+		return o.jsonPath.execute(o.w, map[string]any{"items": o.rows})
+
+	case "go-template":
+		return o.template.Execute(o.w, map[string]any{"items": o.rows})
+	}
+
+	return nil
+}
+
+// sort orders the rows by the --sort-by column, numbers by value and
+// everything else as text, keeping the server's order for equal rows.
+func (o *Writer) sort() error {
+	if len(o.pending) == 0 {
+		return nil
+	}
+
+	if o.opts.SortBy != "" {
+		i, err := column(o.pending[0].row.Type(), o.opts.SortBy)
+		if err != nil {
+			return err
+		}
+
+		slices.SortStableFunc(o.pending, func(a, b pending) int {
+			return compareValues(a.row.Field(i), b.row.Field(i))
+		})
+	}
+
+	if o.opts.Reverse {
+		slices.Reverse(o.pending)
+	}
+
+	return nil
+}
+
+func compareValues(a, b reflect.Value) int {
+	switch {
+	case a.CanInt():
+		return cmp.Compare(a.Int(), b.Int())
+	case a.CanUint():
+		return cmp.Compare(a.Uint(), b.Uint())
+	case a.CanFloat():
+		return cmp.Compare(a.Float(), b.Float())
+	}
+
+	return strings.Compare(text(a), text(b))
+}
+
+// convert copies a row into a struct type built for the format: only the
+// selected columns, and with json and yaml keys for the encoders.
+func (o *Writer) convert(v reflect.Value) (reflect.Value, error) {
+	typ, ok := o.types[v.Type()]
+	if !ok {
+		index, err := o.fields(v.Type())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		k := keys(v.Type())
+		fields := make([]reflect.StructField, 0, len(index))
+
+		for _, i := range index {
+			f := v.Type().Field(i)
+
+			f.Tag = ""
+			if o.table == nil {
+				f.Tag = reflect.StructTag(fmt.Sprintf(`json:%q yaml:%q`, k[i], k[i]))
+			}
+
+			f.Index = nil
+			f.Offset = 0
+			fields = append(fields, f)
+		}
+
+		typ = reflect.StructOf(fields)
+		o.types[v.Type()] = typ
+	}
+
+	out := reflect.New(typ).Elem()
+
+	for i := range typ.NumField() {
+		out.Field(i).Set(v.FieldByName(typ.Field(i).Name))
+	}
+
+	return out, nil
+}
+
+// fields returns the indexes of the columns to write: the --columns in the
+// order given, otherwise every field except the wide ones in a table.
+func (o *Writer) fields(typ reflect.Type) ([]int, error) {
+	var index []int
+
+	if len(o.opts.Columns) == 0 {
+		for i := range typ.NumField() {
+			if o.format == "" || o.format == "table" {
+				if typ.Field(i).Tag.Get("output") == "wide" {
+					continue
+				}
+			}
+
+			index = append(index, i)
+		}
+
+		return index, nil
+	}
+
+	for _, c := range o.opts.Columns {
+		i, err := column(typ, c)
+		if err != nil {
+			return nil, err
+		}
+
+		index = append(index, i)
+	}
+
+	return index, nil
+}
+
+// column returns the index of a column given by field name or key, ignoring
+// case, so TimeZone, timezone and time_zone are the same.
+func column(typ reflect.Type, name string) (int, error) {
+	i := slices.IndexFunc(keys(typ), func(key string) bool {
+		return strings.EqualFold(strings.ReplaceAll(key, "_", ""), strings.ReplaceAll(name, "_", ""))
+	})
+	if i < 0 {
+		names := make([]string, typ.NumField())
+		for i := range names {
+			names[i] = typ.Field(i).Name
+		}
+
+		return 0, fmt.Errorf("unknown column %q, want one of %s", name, strings.Join(names, ","))
+	}
+
+	return i, nil
+}
+
+// keys returns the snake_case names of a row's fields, "time_zone" for
+// TimeZone and "mac" for MAC.
+func keys(typ reflect.Type) []string {
+	k := make([]string, typ.NumField())
+
+	for i := range k {
+		var (
+			b    strings.Builder
+			prev rune
+		)
+
+		for _, r := range typ.Field(i).Name {
+			if unicode.IsUpper(r) && unicode.IsLower(prev) {
+				b.WriteByte('_')
+			}
+
+			b.WriteRune(unicode.ToLower(r))
+			prev = r
+		}
+
+		k[i] = b.String()
+	}
+
+	return k
+}
+
+// text is a cell for csv and name output. Strings are written as is, so
+// types that shorten their value for the table are shown in full.
+func text(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// selector is one term of --where or --selector. Key is a column, like
+// "zone", or an attr, like "attr.os".
+type selector struct {
+	key   string
+	op    string // "=", "!=", "" for exists and "!" for missing
+	value string // may be a glob
+}
+
+// parseWhere parses comma separated terms: key=value, key!=value, key (set
+// and not empty) and !key (missing or empty).
+func parseWhere(s string) ([]selector, error) {
+	var sels []selector
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+
+		var sel selector
+
+		switch {
+		case term == "":
+			continue
+
+		case strings.Contains(term, "!="):
+			k, v, _ := strings.Cut(term, "!=")
+			sel = selector{key: k, op: "!=", value: v}
+
+		case strings.Contains(term, "="):
+			k, v, _ := strings.Cut(term, "=")
+			sel = selector{key: k, op: "=", value: strings.TrimPrefix(v, "=")}
+
+		case strings.HasPrefix(term, "!"):
+			sel = selector{key: term[1:], op: "!"}
+
+		default:
+			sel = selector{key: term}
+		}
+
+		sel.key, sel.value = strings.TrimSpace(sel.key), strings.TrimSpace(sel.value)
+		if sel.key == "" {
+			return nil, fmt.Errorf("invalid selector %q", term)
+		}
+
+		if _, err := path.Match(sel.value, ""); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", term, err)
+		}
+
+		sels = append(sels, sel)
+	}
+
+	return sels, nil
+}
+
+// parseSelector parses a label style selector, where every key is an attr:
+// "role=compute,!power" is the same as --where attr.role=compute,!attr.power.
+func parseSelector(s string) ([]selector, error) {
+	sels, err := parseWhere(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sels {
+		sels[i].key = "attr." + sels[i].key
+	}
+
+	return sels, nil
+}
+
+func (s *selector) match(value string, found bool) bool {
+	switch s.op {
+	case "":
+		return found && value != ""
+	case "!":
+		return !found || value == ""
+	}
+
+	ok, _ := path.Match(s.value, value)
+	if s.op == "!=" {
+		return !found || !ok
+	}
+
+	return found && ok
+}
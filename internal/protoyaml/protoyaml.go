@@ -0,0 +1,352 @@
+// Package protoyaml reads and writes protobuf messages as YAML by way of
+// their JSON mapping, with proto field names.
+//
+// Marshal writes fields in proto declaration order and lists of named objects
+// sorted by name, so dumping the same data twice gives the same bytes.
+// Comments read from an earlier version of a document are kept on the values
+// they were attached to, found by name rather than position, so reordering
+// or adding objects does not move them.
+package protoyaml
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Comments are the comments of a document by the path of the value they
+// belong to, with objects in lists given by name, like
+// $.zones[east].racks[r1].
+type Comments map[string][]*yaml.Comment
+
+// Marshal returns m as YAML, with comments attached where their values are
+// still present.
+func Marshal(m proto.Message, comments Comments) ([]byte, error) {
+	b, err := protojson.MarshalOptions{
+		UseProtoNames: true,
+	}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	tree, err := decode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	tree = sortNamed(tree)
+
+	cm := make(yaml.CommentMap)
+
+	for path, c := range comments {
+		if p, ok := resolve(tree, path); ok {
+			cm[p] = c
+		}
+	}
+
+	return yaml.MarshalWithOptions(tree, yaml.WithComment(cm))
+}
+
+// Unmarshal reads YAML into m. JSON is read too, being YAML, and fields may
+// be given by their proto or JSON names.
+func Unmarshal(b []byte, m proto.Message) error {
+	var tree any
+
+	if err := yaml.Unmarshal(b, &tree); err != nil {
+		return err
+	}
+
+	proto.Reset(m)
+
+	if tree == nil {
+		return nil
+	}
+
+	j, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	return protojson.Unmarshal(j, m)
+}
+
+// ReadComments returns the comments of a YAML document.
+func ReadComments(b []byte) (Comments, error) {
+	var tree any
+
+	cm := make(yaml.CommentMap)
+
+	if err := yaml.UnmarshalWithOptions(b, &tree, yaml.CommentToMap(cm)); err != nil {
+		return nil, err
+	}
+
+	comments := make(Comments, len(cm))
+
+	for path, c := range cm {
+		comments[name(tree, path)] = c
+	}
+
+	return comments, nil
+}
+
+// decode reads a JSON value keeping the order of object keys.
+func decode(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			var m yaml.MapSlice
+
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				v, err := decode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				m = append(m, yaml.MapItem{Key: k, Value: v})
+			}
+
+			_, err := dec.Token()
+
+			return m, err
+
+		case '[':
+			l := []any{}
+
+			for dec.More() {
+				v, err := decode(dec)
+				if err != nil {
+					return nil, err
+				}
+
+				l = append(l, v)
+			}
+
+			_, err := dec.Token()
+
+			return l, err
+		}
+
+	case json.Number:
+		if i, err := tok.Int64(); err == nil {
+			return i, nil
+		}
+
+		return tok.Float64()
+	}
+
+	return tok, nil
+}
+
+// sortNamed sorts the lists of named objects by name. Other lists keep their
+// order, which may matter.
+func sortNamed(v any) any {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for i := range v {
+			v[i].Value = sortNamed(v[i].Value)
+		}
+
+	case []any:
+		for i := range v {
+			v[i] = sortNamed(v[i])
+		}
+
+		if named(v) {
+			slices.SortStableFunc(v, func(a, b any) int {
+				x, _ := nameOf(a)
+				y, _ := nameOf(b)
+
+				return cmp.Compare(x, y)
+			})
+		}
+	}
+
+	return v
+}
+
+// segment is one step of a comment path, a key as written in the path or a
+// list element.
+type segment struct {
+	raw   string // as written, like .zones or [0]
+	key   string
+	index string // the element index or name, without brackets
+}
+
+// split splits a YAML path such as $.zones[0].'a.b' into its segments.
+func split(path string) []segment {
+	var segs []segment
+
+	s := strings.TrimPrefix(path, "$")
+
+	for s != "" {
+		var seg segment
+
+		switch s[0] {
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				end = len(s) - 1
+			}
+
+			seg = segment{raw: s[:end+1], index: s[1:end]}
+
+		case '.':
+			end := 1
+
+			if len(s) > 1 && s[1] == '\'' {
+				end = strings.IndexByte(s[2:], '\'') + 3
+				if end < 3 {
+					end = len(s)
+				}
+
+				seg = segment{raw: s[:end], key: strings.Trim(s[1:end], "'")}
+			} else {
+				for end < len(s) && s[end] != '.' && s[end] != '[' {
+					end++
+				}
+
+				seg = segment{raw: s[:end], key: s[1:end]}
+			}
+
+		default:
+			seg = segment{raw: s, key: s}
+		}
+
+		segs = append(segs, seg)
+		s = s[len(seg.raw):]
+	}
+
+	return segs
+}
+
+// name rewrites the list indexes of a path into names, for lists of named
+// objects in tree.
+func name(tree any, path string) string {
+	out := "$"
+
+	for _, seg := range split(path) {
+		switch v := tree.(type) {
+		case map[string]any:
+			tree = v[seg.key]
+		case []any:
+			i, err := strconv.Atoi(seg.index)
+			if err != nil || i < 0 || i >= len(v) {
+				tree = nil
+
+				break
+			}
+
+			tree = v[i]
+
+			if named(v) {
+				n, _ := nameOf(tree)
+				seg.raw = "[" + n + "]"
+			}
+		default:
+			tree = nil
+		}
+
+		out += seg.raw
+	}
+
+	return out
+}
+
+// resolve is the reverse of name, turning names back into the indexes of the
+// lists in tree. It fails when a value on the path is missing.
+func resolve(tree any, path string) (string, bool) {
+	out := "$"
+
+	for _, seg := range split(path) {
+		switch v := tree.(type) {
+		case yaml.MapSlice:
+			i := slices.IndexFunc(v, func(item yaml.MapItem) bool {
+				return item.Key == seg.key
+			})
+			if i < 0 || seg.key == "" {
+				return "", false
+			}
+
+			tree = v[i].Value
+
+		case []any:
+			i := -1
+
+			if named(v) {
+				i = slices.IndexFunc(v, func(e any) bool {
+					n, _ := nameOf(e)
+
+					return n == seg.index
+				})
+			} else if n, err := strconv.Atoi(seg.index); err == nil && n < len(v) {
+				i = n
+			}
+
+			if i < 0 {
+				return "", false
+			}
+
+			tree = v[i]
+			seg.raw = fmt.Sprintf("[%d]", i)
+
+		default:
+			return "", false
+		}
+
+		out += seg.raw
+	}
+
+	return out, true
+}
+
+// named reports whether every element of a list is an object with a name.
+func named(l []any) bool {
+	for _, e := range l {
+		if _, ok := nameOf(e); !ok {
+			return false
+		}
+	}
+
+	return len(l) > 0
+}
+
+func nameOf(v any) (string, bool) {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			if item.Key == "name" {
+				s, ok := item.Value.(string)
+
+				return s, ok
+			}
+		}
+	case map[string]any:
+		s, ok := v["name"].(string)
+
+		return s, ok
+	}
+
+	return "", false
+}
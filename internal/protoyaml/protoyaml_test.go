@@ -0,0 +1,123 @@
+package protoyaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func schema(t *testing.T, zones ...string) *structpb.Struct {
+	t.Helper()
+
+	var l []any
+
+	for _, z := range zones {
+		l = append(l, map[string]any{
+			"name":  z,
+			"racks": []any{map[string]any{"name": z + "-r1"}},
+		})
+	}
+
+	s, err := structpb.NewStruct(map[string]any{"zones": l})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// TestRoundTrip dumps a schema, comments the dump, and dumps again with
+// a zone added ahead of the commented ones, as dump --update does.
+func TestRoundTrip(t *testing.T) {
+	b, err := Marshal(schema(t, "east", "west"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := strings.NewReplacer(
+		"- name: east\n", "# the east zone\n- name: east\n",
+		"  - name: west-r1\n", "  # west's only rack\n  - name: west-r1\n",
+	).Replace(string(b))
+
+	if edited == string(b) {
+		t.Fatalf("no places to comment in\n%s", b)
+	}
+
+	comments, err := ReadComments([]byte(edited))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = Marshal(schema(t, "west", "central", "east"), comments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadComments(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, c := range comments {
+		if !reflect.DeepEqual(got[path], c) {
+			t.Errorf("comment at %s lost or moved in\n%s", path, b)
+		}
+	}
+
+	if len(got) != len(comments) {
+		t.Errorf("got %d comments, want %d in\n%s", len(got), len(comments), b)
+	}
+
+	var doc structpb.Struct
+
+	if err := Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := schema(t, "central", "east", "west"); !proto.Equal(&doc, want) {
+		t.Errorf("read back %v, want %v", &doc, want)
+	}
+}
+
+// TestFieldOrder dumps a message whose fields are declared out of
+// alphabetical order and checks they come out as declared.
+func TestFieldOrder(t *testing.T) {
+	m := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("rack"),
+		Extendee: proto.String(".metal.Host"),
+		Number:   proto.Int32(7),
+	}
+
+	b, err := Marshal(m, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+
+	for _, line := range strings.Split(string(b), "\n") {
+		if key, _, ok := strings.Cut(line, ":"); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	// Declared as name, number and then extendee, alphabetically the last
+	// goes first.
+	if want := []string{"name", "number", "extendee"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q, want %q in\n%s", keys, want, b)
+	}
+
+	var got descriptorpb.FieldDescriptorProto
+
+	if err := Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !proto.Equal(&got, m) {
+		t.Errorf("read back %v, want %v", &got, m)
+	}
+}
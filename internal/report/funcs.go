@@ -0,0 +1,275 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// helpers is a small subset of the sprig function library, plus address
+// arithmetic for the network oriented templates.
+var helpers = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+	"join":       join,
+	"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+	"quote":      strconv.Quote,
+	"squote":     func(s string) string { return "'" + s + "'" },
+	"indent":     indent,
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"default":    defaultValue,
+	"empty":      empty,
+	"list":       func(v ...any) []any { return v },
+	"sortAlpha":  sortAlpha,
+	"add":        func(a, b int) int { return a + b },
+	"sub":        func(a, b int) int { return a - b },
+	"toJson":     toJSON,
+	"now":        time.Now,
+	"date":       func(layout string, t time.Time) string { return t.Format(layout) },
+
+	"isIPv4":       isIPv4,
+	"isIPv6":       isIPv6,
+	"cidrNetwork":  cidrNetwork,
+	"cidrNetmask":  cidrNetmask,
+	"cidrLength":   cidrLength,
+	"cidrHost":     cidrHost,
+	"reverseName":  reverseName,
+	"reverseZones": reverseZones,
+}
+
+// title capitalizes the first letter of each word, as sprig's title does.
+func title(s string) string {
+	word := false
+
+	return strings.Map(func(r rune) rune {
+		first := !word
+		word = unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+
+		if first {
+			return unicode.ToTitle(r)
+		}
+
+		return r
+	}, s)
+}
+
+func join(sep string, v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Sprint(v)
+	}
+
+	s := make([]string, rv.Len())
+	for i := range s {
+		s[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+
+	return strings.Join(s, sep)
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func empty(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	return reflect.ValueOf(v).IsZero()
+}
+
+func defaultValue(def, v any) any {
+	if empty(v) {
+		return def
+	}
+
+	return v
+}
+
+func sortAlpha(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []string{fmt.Sprint(v)}
+	}
+
+	s := make([]string, rv.Len())
+	for i := range s {
+		s[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+
+	slices.Sort(s)
+
+	return s
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+
+	return string(b), err
+}
+
+func isIPv4(s string) bool {
+	addr, err := netip.ParseAddr(s)
+
+	return err == nil && addr.Unmap().Is4()
+}
+
+func isIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+
+	return err == nil && !addr.Unmap().Is4()
+}
+
+// cidrNetwork returns the network address of a CIDR, "10.1.0.0" for
+// "10.1.0.0/16".
+func cidrNetwork(cidr string) (string, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	return p.Masked().Addr().String(), nil
+}
+
+// cidrNetmask returns the dotted netmask of a CIDR, "255.255.0.0" for
+// "10.1.0.0/16".
+func cidrNetmask(cidr string) (string, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	mask := net.CIDRMask(p.Bits(), p.Addr().BitLen())
+
+	return net.IP(mask).String(), nil
+}
+
+func cidrLength(cidr string) (int, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return 0, err
+	}
+
+	return p.Bits(), nil
+}
+
+// cidrHost returns the n'th address in a CIDR, counting from the network
+// address.
+func cidrHost(n int, cidr string) (string, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	addr := p.Masked().Addr()
+	for range n {
+		addr = addr.Next()
+	}
+
+	if !p.Contains(addr) {
+		return "", fmt.Errorf("host %d is outside %s", n, p)
+	}
+
+	return addr.String(), nil
+}
+
+// reverseName returns the PTR record name for an address.
+func reverseName(ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+
+	addr = addr.Unmap()
+
+	return reverse(addr, addr.BitLen()), nil
+}
+
+// reverseZones returns the reverse DNS zones covering a CIDR. Zones are cut
+// on whole octets (nibbles for IPv6), so a prefix between them, like a /22,
+// is covered by several zones, four /24s. A prefix longer than a /24 (/124)
+// is in the one zone holding it.
+func reverseZones(cidr string) ([]string, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	size, base := 8, 10
+	if p.Addr().Is6() {
+		size, base = 4, 16
+	}
+
+	bits := min(p.Bits(), p.Addr().BitLen()-size)
+	zone := reverse(p.Masked().Addr(), bits)
+
+	spare := (size - bits%size) % size
+	if spare == 0 {
+		return []string{zone}, nil
+	}
+
+	// The prefix ends within the zone's first label, which takes every value
+	// the spare bits allow.
+	first, rest, _ := strings.Cut(zone, ".")
+
+	n, err := strconv.ParseUint(first, base, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]string, 1<<spare)
+	for i := range zones {
+		zones[i] = strconv.FormatUint(n+uint64(i), base) + "." + rest
+	}
+
+	return zones, nil
+}
+
+func reverse(addr netip.Addr, bits int) string {
+	var labels []string
+
+	if addr.Is4() {
+		b := addr.As4()
+		for i := range (bits + 7) / 8 {
+			labels = append(labels, strconv.Itoa(int(b[i])))
+		}
+
+		slices.Reverse(labels)
+
+		return strings.Join(append(labels, "in-addr.arpa"), ".")
+	}
+
+	b := addr.As16()
+	for i := range (bits + 3) / 4 {
+		nibble := b[i/2] >> 4
+		if i%2 == 1 {
+			nibble = b[i/2] & 0x0f
+		}
+
+		labels = append(labels, strconv.FormatUint(uint64(nibble), 16))
+	}
+
+	slices.Reverse(labels)
+
+	return strings.Join(append(labels, "ip6.arpa"), ".")
+}
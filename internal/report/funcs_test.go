@@ -0,0 +1,95 @@
+package report
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestTitle(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"east":           "East",
+		"hello world":    "Hello World",
+		"rack-r1 row_a2": "Rack-R1 Row_a2",
+		"élan vital":     "Élan Vital",
+	}
+
+	for s, want := range tests {
+		if got := title(s); got != want {
+			t.Errorf("title(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestCIDRHost(t *testing.T) {
+	tests := []struct {
+		n    int
+		cidr string
+		want string
+		err  bool
+	}{
+		{n: 0, cidr: "10.1.0.0/24", want: "10.1.0.0"},
+		{n: 1, cidr: "10.1.0.0/24", want: "10.1.0.1"},
+		{n: 1, cidr: "10.1.0.7/24", want: "10.1.0.1"},
+		{n: 255, cidr: "10.1.0.0/24", want: "10.1.0.255"},
+		{n: 256, cidr: "10.1.0.0/24", err: true},
+		{n: 16, cidr: "fd00::/64", want: "fd00::10"},
+		{n: 1, cidr: "10.1.0.0", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := cidrHost(tt.n, tt.cidr)
+		if (err != nil) != tt.err {
+			t.Errorf("cidrHost(%d, %q) error %v, want error %v", tt.n, tt.cidr, err, tt.err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("cidrHost(%d, %q) = %q, want %q", tt.n, tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestReverseZones(t *testing.T) {
+	twelve := make([]string, 0, 16)
+	for i := 16; i < 32; i++ {
+		twelve = append(twelve, strconv.Itoa(i)+".172.in-addr.arpa")
+	}
+
+	tests := []struct {
+		cidr string
+		want []string
+	}{
+		{cidr: "10.0.0.0/8", want: []string{"10.in-addr.arpa"}},
+		{cidr: "172.16.0.0/12", want: twelve},
+		{cidr: "10.1.4.0/22", want: []string{
+			"4.1.10.in-addr.arpa",
+			"5.1.10.in-addr.arpa",
+			"6.1.10.in-addr.arpa",
+			"7.1.10.in-addr.arpa",
+		}},
+		{cidr: "192.168.1.0/24", want: []string{"1.168.192.in-addr.arpa"}},
+		{cidr: "192.168.1.128/25", want: []string{"1.168.192.in-addr.arpa"}},
+		{cidr: "fd00:0:0:10::/62", want: []string{
+			"0.1.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa",
+			"1.1.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa",
+			"2.1.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa",
+			"3.1.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa",
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := reverseZones(tt.cidr)
+		if err != nil {
+			t.Errorf("reverseZones(%q): %v", tt.cidr, err)
+
+			continue
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("reverseZones(%q) = %q, want %q", tt.cidr, got, tt.want)
+		}
+	}
+}
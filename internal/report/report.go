@@ -0,0 +1,151 @@
+// Package report renders metal objects through text/template.
+package report
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"endobit.io/metal"
+)
+
+// Built-in templates are in a directory per object, since each is written
+// for what dot holds, like templates/zone for zones.
+//
+//go:embed templates
+var builtins embed.FS
+
+type Renderer struct {
+	Client *metal.Client
+	Object string // the kind of object rendered, which picks the built-ins
+}
+
+// Builtins returns the names of the templates compiled into stack for an
+// object.
+func Builtins(object string) []string {
+	entries, _ := builtins.ReadDir(path.Join("templates", object))
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".tmpl"))
+	}
+
+	return names
+}
+
+// Render executes the named template with data as dot. The name is a built-in
+// template, or a file path when it has a directory or an extension or no
+// built-in is named so.
+func (r *Renderer) Render(w io.Writer, name string, data any) error {
+	text, err := load(name, r.Object)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New(filepath.Base(name)).
+		Option("missingkey=error").
+		Funcs(r.funcs()).
+		Parse(text)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, data)
+}
+
+// load reads a template. A name with a directory or an extension is a file,
+// as is one no built-in template has, so a file named like a built-in in the
+// current directory does not hide it.
+func load(name, object string) (string, error) {
+	if name == "" {
+		return "", errors.New("no template given")
+	}
+
+	if filepath.Base(name) != name || filepath.Ext(name) != "" {
+		b, err := os.ReadFile(name)
+		return string(b), err
+	}
+
+	if b, err := builtins.ReadFile(path.Join("templates", object, name+".tmpl")); err == nil {
+		return string(b), nil
+	}
+
+	if b, err := os.ReadFile(name); err == nil {
+		return string(b), nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	names := Builtins(object)
+	if len(names) == 0 {
+		return "", fmt.Errorf("unknown template %q, there are no built-in %s templates", name, object)
+	}
+
+	return "", fmt.Errorf("unknown template %q, built-in %s templates are %s",
+		name, object, strings.Join(names, ", "))
+}
+
+func (r *Renderer) funcs() template.FuncMap {
+	c := r.Client
+
+	m := template.FuncMap{
+		"zones": func(glob string) (any, error) {
+			return all(c.NewZoneReader(glob).Responses())
+		},
+		"clusters": func(zone, glob string) (any, error) {
+			return all(c.NewClusterReader(zone, glob).Responses())
+		},
+		"racks": func(zone, glob string) (any, error) {
+			return all(c.NewRackReader(zone, glob).Responses())
+		},
+		"appliances": func(zone, glob string) (any, error) {
+			return all(c.NewApplianceReader(zone, glob).Responses())
+		},
+		"environments": func(zone, glob string) (any, error) {
+			return all(c.NewEnvironmentReader(zone, glob).Responses())
+		},
+		"hosts": func(zone, glob string) (any, error) {
+			return all(c.NewHostReader(zone, glob).Responses())
+		},
+		"interfaces": func(zone, host, glob string) (any, error) {
+			return all(c.NewHostInterfaceReader(zone, host, glob).Responses())
+		},
+		"networks": func(zone, glob string) (any, error) {
+			return all(c.NewNetworkReader(zone, glob).Responses())
+		},
+		"makes": func(glob string) (any, error) {
+			return all(c.NewMakeReader(glob).Responses())
+		},
+		"models": func(vendor, glob string) (any, error) {
+			return all(c.NewModelReader(vendor, glob).Responses())
+		},
+	}
+
+	for k, v := range helpers {
+		m[k] = v
+	}
+
+	return m
+}
+
+func all[T any](seq iter.Seq2[T, error]) (any, error) {
+	var s []T
+
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, v)
+	}
+
+	return s, nil
+}
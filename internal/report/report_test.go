@@ -0,0 +1,53 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoad checks a built-in template is used over a file of the same name in
+// the current directory, and that a name with a directory, an extension or no
+// built-in is read as a file.
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	for _, name := range []string{"hosts", "hosts.tmpl", "mine"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("file "+name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	builtin, err := builtins.ReadFile("templates/zone/hosts.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{name: "hosts", want: string(builtin)},
+		{name: "./hosts", want: "file hosts"},
+		{name: filepath.Join(dir, "hosts"), want: "file hosts"},
+		{name: "hosts.tmpl", want: "file hosts.tmpl"},
+		{name: "mine", want: "file mine"},
+		{name: "missing", err: true},
+		{name: "", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := load(tt.name, "zone")
+		if (err != nil) != tt.err {
+			t.Errorf("load(%q) error %v, want error %v", tt.name, err, tt.err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("load(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package tui draws full screen terminal interfaces with ANSI escapes.
+//
+// A Screen puts the terminal in raw mode on the alternate screen. Each frame
+// is drawn into a buffer, starting with Clear, and written at once with
+// Flush so the terminal never shows half a frame.
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Code is the kind of key read by ReadKey.
+type Code int
+
+const (
+	Unknown Code = iota
+	Rune         // a printable character, in Key.Rune
+	Ctrl         // a control character, the letter in Key.Rune
+	Enter
+	Esc
+	Backspace
+	Delete
+	Tab
+	Up
+	Down
+	Left
+	Right
+	PageUp
+	PageDown
+	Home
+	End
+)
+
+// Key is one key press.
+type Key struct {
+	Code Code
+	Rune rune
+}
+
+// Style is a set of SGR attributes for Print.
+type Style string
+
+const (
+	Plain   Style = ""
+	Bold    Style = "\x1b[1m"
+	Dim     Style = "\x1b[2m"
+	Reverse Style = "\x1b[7m"
+	Red     Style = "\x1b[31m"
+	Cyan    Style = "\x1b[36m"
+)
+
+// Screen is a terminal in raw mode.
+type Screen struct {
+	in     *os.File
+	out    *os.File
+	state  *term.State
+	r      *bufio.Reader
+	buf    bytes.Buffer
+	width  int
+	height int
+}
+
+// Open switches the terminal to raw mode and the alternate screen. Close
+// must be called to restore it.
+func Open(in, out *os.File) (*Screen, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) { //nolint:gosec
+		return nil, errors.New("stdin and stdout must be a terminal")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd())) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	s := Screen{
+		in:    in,
+		out:   out,
+		state: state,
+		r:     bufio.NewReader(in),
+	}
+
+	// Alternate screen, hidden cursor.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+
+	return &s, nil
+}
+
+// Close restores the terminal.
+func (s *Screen) Close() error {
+	fmt.Fprint(s.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+
+	return term.Restore(int(s.in.Fd()), s.state) //nolint:gosec
+}
+
+// Clear starts a new frame the size of the terminal.
+func (s *Screen) Clear() {
+	w, h, err := term.GetSize(int(s.out.Fd())) //nolint:gosec
+	if err != nil {
+		w, h = 80, 24
+	}
+
+	s.width, s.height = w, h
+
+	s.buf.Reset()
+	s.buf.WriteString("\x1b[0m\x1b[H\x1b[2J")
+}
+
+// Size returns the size of the frame in cells.
+func (s *Screen) Size() (width, height int) {
+	return s.width, s.height
+}
+
+// Print writes text at a row and column, both from 0, cut or padded to
+// width cells and clipped to the screen.
+func (s *Screen) Print(row, col, width int, style Style, text string) {
+	if row < 0 || row >= s.height || col < 0 {
+		return
+	}
+
+	width = min(width, s.width-col)
+	if width <= 0 {
+		return
+	}
+
+	fmt.Fprintf(&s.buf, "\x1b[%d;%dH%s%s\x1b[0m", row+1, col+1, style, fit(text, width))
+}
+
+// Flush writes the frame to the terminal.
+func (s *Screen) Flush() error {
+	_, err := s.out.Write(s.buf.Bytes())
+
+	return err
+}
+
+// fit cuts or pads text to width runes, ending cut text with an ellipsis.
+// Control characters are shown as spaces so text cannot move the cursor.
+func fit(text string, width int) string {
+	r := []rune(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+
+		return r
+	}, text))
+
+	if len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+
+	return string(r) + strings.Repeat(" ", width-len(r))
+}
+
+// ReadKey waits for a key press.
+func (s *Screen) ReadKey() (Key, error) {
+	r, _, err := s.r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch r {
+	case '\r', '\n':
+		return Key{Code: Enter}, nil
+	case '\t':
+		return Key{Code: Tab}, nil
+	case 0x7f, '\b':
+		return Key{Code: Backspace}, nil
+	case 0x1b:
+		// A lone escape, or the start of a sequence which the terminal
+		// sends in one write.
+		if s.r.Buffered() == 0 {
+			return Key{Code: Esc}, nil
+		}
+
+		return s.readEscape()
+	}
+
+	if r < 0x20 {
+		return Key{Code: Ctrl, Rune: r + 'a' - 1}, nil
+	}
+
+	return Key{Code: Rune, Rune: r}, nil
+}
+
+// readEscape reads the rest of a CSI or SS3 sequence, like \x1b[A for up.
+func (s *Screen) readEscape() (Key, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+
+	if b != '[' && b != 'O' {
+		return Key{Code: Unknown}, nil // alt and a key
+	}
+
+	var params []byte
+
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+
+		if c >= 0x40 && c <= 0x7e {
+			b = c
+
+			break
+		}
+
+		params = append(params, c)
+	}
+
+	switch b {
+	case 'A':
+		return Key{Code: Up}, nil
+	case 'B':
+		return Key{Code: Down}, nil
+	case 'C':
+		return Key{Code: Right}, nil
+	case 'D':
+		return Key{Code: Left}, nil
+	case 'H':
+		return Key{Code: Home}, nil
+	case 'F':
+		return Key{Code: End}, nil
+	case '~':
+		switch string(params) {
+		case "1", "7":
+			return Key{Code: Home}, nil
+		case "3":
+			return Key{Code: Delete}, nil
+		case "4", "8":
+			return Key{Code: End}, nil
+		case "5":
+			return Key{Code: PageUp}, nil
+		case "6":
+			return Key{Code: PageDown}, nil
+		}
+	}
+
+	return Key{Code: Unknown}, nil
+}
@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	s := Screen{r: bufio.NewReader(strings.NewReader(
+		"a\r\t\x7f\x03é" +
+			"\x1b[A\x1b[B\x1bOC\x1bOD\x1b[H\x1b[F" +
+			"\x1b[1~\x1b[3~\x1b[4~\x1b[5~\x1b[6~\x1b[1;5A\x1b[9~\x1bx" +
+			"\x1b"))}
+
+	want := []Key{
+		{Code: Rune, Rune: 'a'},
+		{Code: Enter},
+		{Code: Tab},
+		{Code: Backspace},
+		{Code: Ctrl, Rune: 'c'},
+		{Code: Rune, Rune: 'é'},
+		{Code: Up},
+		{Code: Down},
+		{Code: Right},
+		{Code: Left},
+		{Code: Home},
+		{Code: End},
+		{Code: Home},
+		{Code: Delete},
+		{Code: End},
+		{Code: PageUp},
+		{Code: PageDown},
+		{Code: Up}, // with ctrl
+		{Code: Unknown},
+		{Code: Unknown}, // alt and x
+		{Code: Esc},
+	}
+
+	for i, w := range want {
+		k, err := s.ReadKey()
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+
+		if k != w {
+			t.Errorf("key %d: got %+v, want %+v", i, k, w)
+		}
+	}
+
+	if _, err := s.ReadKey(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v at the end, want EOF", err)
+	}
+}
+
+// TestReadEscapeCut reads a sequence cut short, which is an error rather
+// than a key.
+func TestReadEscapeCut(t *testing.T) {
+	s := Screen{r: bufio.NewReader(strings.NewReader("[1;"))}
+
+	if _, err := s.readEscape(); !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want EOF", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"rack", 6, "rack  "},
+		{"rack", 4, "rack"},
+		{"rack-01", 5, "rack…"},
+		{"zoné", 3, "zo…"},
+		{"a\x1b[2Jb", 6, "a [2Jb"},
+		{"a\nb\tc", 5, "a b c"},
+		{"", 2, "  "},
+	}
+
+	for _, tt := range tests {
+		if got := fit(tt.text, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+)
+
+// List is the cursor and scroll position of a list of rows.
+type List struct {
+	Cursor int
+	offset int
+}
+
+// Handle moves the cursor for the navigation keys, in a list of length rows
+// shown height at a time. It reports whether the key was one of them.
+func (l *List) Handle(k Key, length, height int) bool {
+	switch k.Code {
+	case Up:
+		l.Cursor--
+	case Down:
+		l.Cursor++
+	case PageUp:
+		l.Cursor -= max(height-1, 1)
+	case PageDown:
+		l.Cursor += max(height-1, 1)
+	case Home:
+		l.Cursor = 0
+	case End:
+		l.Cursor = length - 1
+	default:
+		return false
+	}
+
+	l.Clamp(length)
+
+	return true
+}
+
+// Clamp keeps the cursor within a list of length rows.
+func (l *List) Clamp(length int) {
+	l.Cursor = max(min(l.Cursor, length-1), 0)
+}
+
+// Draw draws the rows that fit in height, scrolled to show the cursor,
+// which is highlighted when the list has the focus.
+func (l *List) Draw(s *Screen, row, col, width, height int, rows []string, focused bool) {
+	l.Clamp(len(rows))
+
+	if l.Cursor < l.offset {
+		l.offset = l.Cursor
+	}
+
+	if l.Cursor >= l.offset+height {
+		l.offset = l.Cursor - height + 1
+	}
+
+	l.offset = max(min(l.offset, len(rows)-height), 0)
+
+	for i := range height {
+		n := l.offset + i
+		if n >= len(rows) {
+			break
+		}
+
+		style := Plain
+
+		if n == l.Cursor {
+			style = Bold
+			if focused {
+				style = Reverse
+			}
+		}
+
+		s.Print(row+i, col, width, style, " "+rows[n])
+	}
+}
+
+// Input is a line of text being edited.
+type Input struct {
+	text []rune
+	pos  int
+}
+
+// NewInput returns an Input holding text, with the cursor at its end.
+func NewInput(text string) *Input {
+	r := []rune(text)
+
+	return &Input{text: r, pos: len(r)}
+}
+
+func (in *Input) String() string {
+	return string(in.text)
+}
+
+// Handle edits the text for a key, reporting whether it was an editing key.
+func (in *Input) Handle(k Key) bool {
+	switch k.Code {
+	case Rune:
+		in.text = slices.Insert(in.text, in.pos, k.Rune)
+		in.pos++
+	case Backspace:
+		if in.pos > 0 {
+			in.text = slices.Delete(in.text, in.pos-1, in.pos)
+			in.pos--
+		}
+	case Delete:
+		if in.pos < len(in.text) {
+			in.text = slices.Delete(in.text, in.pos, in.pos+1)
+		}
+	case Left:
+		in.pos = max(in.pos-1, 0)
+	case Right:
+		in.pos = min(in.pos+1, len(in.text))
+	case Home:
+		in.pos = 0
+	case End:
+		in.pos = len(in.text)
+	case Ctrl:
+		if k.Rune != 'u' {
+			return false
+		}
+
+		in.text = in.text[in.pos:]
+		in.pos = 0
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Draw draws the text with the cursor shown in reverse, scrolled so the
+// cursor fits in width.
+func (in *Input) Draw(s *Screen, row, col, width int) {
+	if width <= 1 {
+		return
+	}
+
+	start := max(in.pos-width+1, 0)
+	text := in.text[start:min(len(in.text), start+width)]
+	cur := in.pos - start
+
+	s.Print(row, col, width, Plain, string(text))
+
+	under := " "
+	if cur < len(text) {
+		under = string(text[cur])
+	}
+
+	s.Print(row, col+cur, 1, Reverse, under)
+}
+
+// Prompt asks for a line of text, starting from value, in a box over the
+// frame drawn by background. It returns false if cancelled with Esc.
+func (s *Screen) Prompt(background func(), title, value string) (string, bool, error) {
+	in := NewInput(value)
+
+	for {
+		background()
+
+		row, col, width := s.box(title, 1)
+		in.Draw(s, row, col, width)
+
+		if err := s.Flush(); err != nil {
+			return "", false, err
+		}
+
+		k, err := s.ReadKey()
+		if err != nil {
+			return "", false, err
+		}
+
+		switch {
+		case k.Code == Enter:
+			return in.String(), true, nil
+		case k.Code == Esc, k.Code == Ctrl && k.Rune == 'c':
+			return "", false, nil
+		}
+
+		in.Handle(k)
+	}
+}
+
+// Confirm asks a yes or no question in a box over the frame drawn by
+// background. Anything but y is no.
+func (s *Screen) Confirm(background func(), question string) (bool, error) {
+	lines := strings.Split(question, "\n")
+
+	background()
+
+	row, col, width := s.box("Confirm", len(lines)+2)
+	for i, line := range lines {
+		s.Print(row+i, col, width, Plain, line)
+	}
+
+	s.Print(row+len(lines)+1, col, width, Dim, "y yes, any other key no")
+
+	if err := s.Flush(); err != nil {
+		return false, err
+	}
+
+	k, err := s.ReadKey()
+	if err != nil {
+		return false, err
+	}
+
+	return k.Code == Rune && (k.Rune == 'y' || k.Rune == 'Y'), nil
+}
+
+// box draws a bordered box with a title in the middle of the screen and
+// returns where its height lines of content go.
+func (s *Screen) box(title string, height int) (row, col, width int) {
+	width = min(max(s.width*2/3, 40), s.width-4)
+	row = max((s.height-height)/2-1, 0)
+	col = (s.width - width - 4) / 2
+
+	s.Print(row, col, width+4, Plain, "┌─ "+title+" "+strings.Repeat("─", max(width-len([]rune(title))-1, 0))+"┐")
+
+	for i := range height {
+		s.Print(row+1+i, col, width+4, Plain, "│"+strings.Repeat(" ", width+2)+"│")
+	}
+
+	s.Print(row+1+height, col, width+4, Plain, "└"+strings.Repeat("─", width+2)+"┘")
+
+	return row + 1, col + 2, width
+}
@@ -2,18 +2,14 @@
 package main
 
 import (
-	"crypto/tls"
+	"fmt"
 	"os"
 	"strconv"
 
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 
 	"endobit.io/metal"
 	"endobit.io/metal-cli/internal/commands"
-	authpb "endobit.io/metal/gen/go/proto/auth/v1"
-	metalpb "endobit.io/metal/gen/go/proto/metal/v1"
 	"endobit.io/metal/logging"
 )
 
@@ -23,65 +19,163 @@ func main() {
 	cmd := newRootCmd()
 	cmd.Version = version
 
-	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+	if c, err := cmd.ExecuteC(); err != nil {
+		os.Exit(commands.ExitCode(c, err))
 	}
 }
 
 func newRootCmd() *cobra.Command {
 	var (
-		username, password, metalServer string
-		rpc                             metal.Client
-		logOpts                         *logging.Options
+		s       session
+		logOpts *logging.Options
 	)
 
+	root := commands.Root{
+		Client:        &s.rpc,
+		Connect:       s.open,
+		Backups:       s.backups,
+		CompletionDir: s.completionDir,
+		Resume: func(cmd *cobra.Command) error {
+			logger, err := logOpts.NewLogger()
+			if err != nil {
+				return err
+			}
+
+			if err := s.connect(cmd, logger); err != nil {
+				return err
+			}
+
+			s.resume()
+
+			return nil
+		},
+	}
+
 	cmd := cobra.Command{
 		Use:   "stack",
 		Short: "Stack Client",
 		Long:  "Stack Command Line Client",
-		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			// Completion connects when it reads the server, see Root.Resume.
+			if cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+				return nil
+			}
+
 			logger, err := logOpts.NewLogger()
 			if err != nil {
 				return err
 			}
 
-			creds := credentials.NewTLS(&tls.Config{
-				InsecureSkipVerify: true, //nolint:gosec
-				MinVersion:         tls.VersionTLS12,
-			})
+			if cmd.Annotations[commands.SkipAuth] == commands.Offline {
+				s.cmd = cmd
 
-			conn, err := grpc.NewClient(metalServer, grpc.WithTransportCredentials(creds))
-			if err != nil {
+				return nil
+			}
+
+			if err := s.connect(cmd, logger); err != nil {
 				return err
 			}
 
-			rpc = metal.Client{
-				Logger: logger,
-				Metal:  metalpb.NewMetalServiceClient(conn),
-				Auth:   authpb.NewAuthServiceClient(conn),
+			if _, ok := cmd.Annotations[commands.SkipAuth]; ok {
+				return nil
 			}
 
-			return rpc.Authorize(username, password)
+			return s.authenticate(cmd.Context())
 		},
 	}
 
 	logOpts = logging.NewOptions(cmd.PersistentFlags())
 
-	cmd.PersistentFlags().StringVar(&username, "username", "admin", "username for authentication")
-	cmd.PersistentFlags().StringVar(&password, "password", "admin", "password for authentication")
-	cmd.PersistentFlags().StringVar(&metalServer, "metal-server", "localhost:"+strconv.Itoa(metal.DefaultPort),
+	cmd.PersistentFlags().StringVar(&s.username, "username", "admin", "username for authentication")
+	cmd.PersistentFlags().StringVar(&s.password, "password", "", "password for authentication (prefer the prompt or --password-stdin)")
+	cmd.PersistentFlags().BoolVar(&s.passwordStdin, "password-stdin", false, "read the password from the first line of stdin")
+	cmd.PersistentFlags().StringVar(&s.metalServer, "metal-server", "localhost:"+strconv.Itoa(metal.DefaultPort),
 		"address of the metal server")
+	cmd.PersistentFlags().StringVar(&s.configFile, "config", "", "configuration file (default $STACK_CONFIG or ~/.config/stack/config.yaml)")
+	cmd.PersistentFlags().StringVar(&s.contextName, "context", "", "configuration context to use (default $STACK_CONTEXT or the current context)")
+	cmd.PersistentFlags().String("ca-file", "", "PEM bundle of CAs to verify the metal server (default system roots)")
+	cmd.PersistentFlags().String("cert", "", "PEM client certificate for mutual TLS")
+	cmd.PersistentFlags().String("key", "", "PEM client key for mutual TLS")
+	cmd.PersistentFlags().String("server-name", "", "override the server name used to verify the metal server")
+	cmd.PersistentFlags().Bool("insecure", false, "do not verify the metal server certificate")
+	cmd.PersistentFlags().Bool("plaintext", false, "connect without TLS, for local development only")
 
-	root := commands.Root{Client: &rpc}
+	cfg := commands.Config{File: &s.configFile, Context: &s.contextName}
 
 	cmd.AddCommand(
 		root.New(commands.Add),
+		root.New(commands.Apply),
+		root.New(commands.Backup),
+		root.New(commands.Diff),
 		root.New(commands.Dump),
+		root.New(commands.Lint),
 		root.New(commands.List),
 		root.New(commands.Load),
 		root.New(commands.Remove),
 		root.New(commands.Report),
-		root.New(commands.Set))
+		root.New(commands.Set),
+		root.New(commands.UI),
+		root.New(commands.Undo),
+		cfg.New(),
+		newLoginCmd(&s),
+		newLogoutCmd(&s))
+
+	root.AddCompletions(&cmd)
+
+	// Writing a completion script needs no server.
+	cmd.InitDefaultCompletionCmd()
+
+	if c, _, err := cmd.Find([]string{"completion"}); err == nil {
+		for _, sub := range c.Commands() {
+			sub.Annotations = map[string]string{commands.SkipAuth: commands.Offline}
+		}
+	}
 
 	return &cmd
 }
+
+func newLoginCmd(s *session) *cobra.Command {
+	return &cobra.Command{
+		Use:         "login",
+		Short:       "Log in and cache a token for the context",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{commands.SkipAuth: ""},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			tok, err := s.login(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("logged in to %s as %s", tok.Server, tok.Username)
+
+			if !tok.Expiry.IsZero() {
+				fmt.Printf(" until %s", tok.Expiry.Local().Format("2006-01-02 15:04"))
+			}
+
+			fmt.Println()
+
+			return nil
+		},
+	}
+}
+
+func newLogoutCmd(s *session) *cobra.Command {
+	return &cobra.Command{
+		Use:         "logout",
+		Short:       "Revoke and remove the context's cached token",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{commands.SkipAuth: ""},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ok, err := s.logout(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				fmt.Println("not logged in to", s.ctx.Server)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"endobit.io/metal"
+	"endobit.io/metal-cli/internal/backup"
+	"endobit.io/metal-cli/internal/config"
+	authpb "endobit.io/metal/gen/go/proto/auth/v1"
+	metalpb "endobit.io/metal/gen/go/proto/metal/v1"
+)
+
+// contextFlags maps root flags to the context settings they override.
+var contextFlags = map[string]string{
+	"metal-server": "server",
+	"username":     "username",
+	"ca-file":      "ca_file",
+	"cert":         "cert",
+	"key":          "key",
+	"server-name":  "server_name",
+	"insecure":     "insecure",
+	"plaintext":    "plaintext",
+}
+
+// session is the connection to the metal server shared by all commands.
+type session struct {
+	username, password, metalServer string
+	configFile, contextName         string
+	passwordStdin                   bool
+
+	cmd   *cobra.Command // the command run, to resolve the context of offline ones
+	ctx   config.Context
+	key   string // token cache key, set once the context is resolved
+	named bool   // opened by name, so the password flags and environment are not its own
+	rpc   metal.Client
+	token tokenCredentials
+}
+
+// tokenCredentials attaches a cached token to every call once one is set.
+type tokenCredentials struct {
+	value  string
+	secure bool
+}
+
+func (t *tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	if t.value == "" {
+		return nil, nil
+	}
+
+	return map[string]string{"authorization": "Bearer " + t.value}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// connect resolves the context and dials the server.
+func (s *session) connect(cmd *cobra.Command, logger *slog.Logger) error {
+	if err := s.resolve(cmd); err != nil {
+		return err
+	}
+
+	if err := setDefaults(cmd, &s.ctx); err != nil {
+		return err
+	}
+
+	return s.dial(logger)
+}
+
+// resolve layers the flags over the environment and configuration context.
+func (s *session) resolve(cmd *cobra.Command) error {
+	cfg, err := config.Load(s.configFile)
+	if err != nil {
+		return err
+	}
+
+	name := cfg.Resolve(s.contextName)
+
+	s.ctx, err = cfg.Context(name)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+
+	for flag, key := range contextFlags {
+		if f := flags.Lookup(flag); f.Changed {
+			if err := s.ctx.Set(key, f.Value.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.ctx.Server == "" {
+		s.ctx.Server = s.metalServer
+	}
+
+	if s.ctx.Username == "" {
+		s.ctx.Username = s.username
+	}
+
+	if flags.Changed("password") && s.passwordStdin {
+		return errors.New("--password and --password-stdin are mutually exclusive")
+	}
+
+	s.key = name
+	if s.key == "" {
+		s.key = s.ctx.Server
+	}
+
+	return nil
+}
+
+// dial creates the client for the session's context.
+func (s *session) dial(logger *slog.Logger) error {
+	creds, err := transportCredentials(&s.ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.ctx.Insecure && !s.ctx.Plaintext {
+		logger.Warn("not verifying the metal server certificate", "server", s.ctx.Server)
+	}
+
+	s.token.secure = !s.ctx.Plaintext
+
+	conn, err := grpc.NewClient(s.ctx.Server,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(&s.token))
+	if err != nil {
+		return err
+	}
+
+	s.rpc = metal.Client{
+		Logger: logger,
+		Metal:  metalpb.NewMetalServiceClient(conn),
+		Auth:   authpb.NewAuthServiceClient(conn),
+	}
+
+	return nil
+}
+
+// open returns a client authenticated to a named context, or to the current
+// one when name is empty. Other contexts are used as configured, without the
+// flags and environment that override the current one.
+func (s *session) open(ctx context.Context, name string) (*metal.Client, error) {
+	if name == "" {
+		return &s.rpc, s.authenticate(ctx)
+	}
+
+	cfg, err := config.Load(s.configFile)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := cfg.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found", name)
+	}
+
+	o := session{
+		ctx:   *p,
+		key:   name,
+		named: true,
+	}
+
+	if o.ctx.Server == "" {
+		o.ctx.Server = s.metalServer
+	}
+
+	if o.ctx.Username == "" {
+		o.ctx.Username = s.username
+	}
+
+	if err := o.dial(s.rpc.Logger); err != nil {
+		return nil, err
+	}
+
+	return &o.rpc, o.authenticate(ctx)
+}
+
+// backups returns the backup history of the session's context, resolving
+// it first for offline commands.
+func (s *session) backups() (*backup.Store, error) {
+	if s.key == "" {
+		if err := s.resolve(s.cmd); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := config.BackupDir(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return backup.New(dir, s.ctx.Backup, s.ctx.BackupKeep)
+}
+
+// completionDir returns where shell completion caches object names.
+func (s *session) completionDir() (string, error) {
+	return config.CompletionDir(s.key)
+}
+
+// resume uses the cached token from stack login if it is still good. Shell
+// completion reads the server with it since completion must never prompt.
+func (s *session) resume() {
+	tok, err := config.LoadToken(s.key)
+	if err != nil || tok == nil || !tok.Matches(s.ctx.Server, s.ctx.Username) || tok.Expired() {
+		return
+	}
+
+	s.token.value = tok.Token
+}
+
+// authenticate uses the cached token from stack login, logging in again if
+// it has expired. Without a cached token for the server and username it
+// authorizes with the username and password.
+func (s *session) authenticate(ctx context.Context) error {
+	tok, err := config.LoadToken(s.key)
+	if err != nil {
+		return err
+	}
+
+	if tok == nil || !tok.Matches(s.ctx.Server, s.ctx.Username) {
+		password, err := s.readPassword(ctx)
+		if err != nil {
+			return err
+		}
+
+		return s.rpc.Authorize(s.ctx.Username, password)
+	}
+
+	if tok.Expired() {
+		if tok, err = s.login(ctx); err != nil {
+			return fmt.Errorf("refreshing expired token: %w", err)
+		}
+	}
+
+	s.token.value = tok.Token
+
+	return nil
+}
+
+// login exchanges the username and password for a token and caches it.
+func (s *session) login(ctx context.Context) (*config.Token, error) {
+	password, err := s.readPassword(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := authpb.LoginRequest_builder{
+		Username: &s.ctx.Username,
+		Password: &password,
+	}.Build()
+
+	resp, err := s.rpc.Auth.Login(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	tok := config.NewToken(s.ctx.Server, s.ctx.Username, resp.GetToken())
+
+	return tok, config.SaveToken(s.key, tok)
+}
+
+// readPassword returns the password from, in order, --password,
+// --password-stdin, $STACK_PASSWORD, the context's password_command, or a
+// prompt on the terminal. A context opened by name, like diff @prod, only uses
+// its own password_command or the prompt, since the others are for the
+// current context's server.
+func (s *session) readPassword(ctx context.Context) (string, error) {
+	switch {
+	case s.password != "":
+		return s.password, nil
+
+	case s.passwordStdin:
+		line, err := readLine(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+
+		s.password = strings.TrimRight(line, "\r\n")
+
+	default:
+		if v, ok := os.LookupEnv("STACK_PASSWORD"); ok && !s.named {
+			s.password = v
+
+			break
+		}
+
+		if s.ctx.PasswordCommand != "" {
+			var out bytes.Buffer
+
+			cmd := exec.CommandContext(ctx, "sh", "-c", s.ctx.PasswordCommand)
+			cmd.Stdout = &out
+			cmd.Stderr = os.Stderr
+
+			// Stdin may be a schema, like load -, so the command gets the
+			// terminal, for a pinentry or the like, or nothing.
+			if f, err := os.Open("/dev/tty"); err == nil {
+				defer f.Close()
+
+				cmd.Stdin = f
+			}
+
+			if err := cmd.Run(); err != nil {
+				return "", fmt.Errorf("password_command: %w", err)
+			}
+
+			s.password = strings.TrimRight(out.String(), "\r\n")
+
+			break
+		}
+
+		// Stdin may be a schema, like load -, so prompt on the terminal.
+		tty := os.Stdin
+
+		if !term.IsTerminal(int(tty.Fd())) { //nolint:gosec
+			f, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+			if err != nil {
+				if s.named {
+					return "", fmt.Errorf("no password for context %q, set its password_command", s.key)
+				}
+
+				return "", errors.New("no password given, use --password-stdin, $STACK_PASSWORD or password_command")
+			}
+
+			defer f.Close()
+
+			tty = f
+		}
+
+		fmt.Fprintf(os.Stderr, "Password for %s@%s: ", s.ctx.Username, s.ctx.Server)
+
+		b, err := term.ReadPassword(int(tty.Fd())) //nolint:gosec
+		fmt.Fprintln(os.Stderr)
+
+		if err != nil {
+			return "", err
+		}
+
+		s.password = string(b)
+	}
+
+	return s.password, nil
+}
+
+// readLine reads up to and including a newline a byte at a time, so the rest
+// of r is left for commands that also read stdin, like load -.
+func readLine(r io.Reader) (string, error) {
+	var (
+		line []byte
+		b    [1]byte
+	)
+
+	for {
+		n, err := r.Read(b[:])
+		if n > 0 {
+			line = append(line, b[0])
+
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return string(line), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// logout revokes the cached token and removes it.
+func (s *session) logout(ctx context.Context) (bool, error) {
+	tok, err := config.LoadToken(s.key)
+	if err != nil || tok == nil {
+		return false, err
+	}
+
+	s.token.value = tok.Token
+
+	if _, err := s.rpc.Auth.Logout(ctx, &authpb.LogoutRequest{}); err != nil {
+		s.rpc.Logger.Warn("cannot revoke token", "err", err)
+	}
+
+	return true, config.DeleteToken(s.key)
+}
+
+// setDefaults fills in the --zone and --cluster flags of the object command
+// being run, like stack list rack, from the context unless they were given on
+// the command line. Other commands, like dump, read the whole schema without
+// them.
+func setDefaults(cmd *cobra.Command, ctx *config.Context) error {
+	if !cmd.HasParent() || !cmd.Parent().HasParent() {
+		return nil
+	}
+
+	flags := cmd.Flags()
+
+	defaults := map[string]string{
+		"zone":    ctx.Zone,
+		"cluster": ctx.Cluster,
+	}
+
+	for name, value := range defaults {
+		f := flags.Lookup(name)
+		if f == nil || f.Changed || value == "" {
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func transportCredentials(ctx *config.Context) (credentials.TransportCredentials, error) {
+	if ctx.Plaintext {
+		return insecure.NewCredentials(), nil
+	}
+
+	cfg := tls.Config{
+		ServerName:         ctx.ServerName,
+		InsecureSkipVerify: ctx.Insecure, //nolint:gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if ctx.CAFile != "" {
+		pem, err := os.ReadFile(ctx.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", ctx.CAFile)
+		}
+	}
+
+	if ctx.Cert != "" || ctx.Key != "" {
+		if ctx.Cert == "" || ctx.Key == "" {
+			return nil, errors.New("mutual TLS needs both a certificate and a key")
+		}
+
+		cert, err := tls.LoadX509KeyPair(ctx.Cert, ctx.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return credentials.NewTLS(&cfg), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"endobit.io/metal-cli/internal/config"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		in, line, rest string
+	}{
+		{in: "secret\nzones: []\n", line: "secret\n", rest: "zones: []\n"},
+		{in: "secret", line: "secret"},
+		{in: "secret\r\nrest", line: "secret\r\n", rest: "rest"},
+		{in: "", line: ""},
+	}
+
+	for _, tt := range tests {
+		r := strings.NewReader(tt.in)
+
+		line, err := readLine(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if line != tt.line {
+			t.Errorf("readLine(%q) = %q, want %q", tt.in, line, tt.line)
+		}
+
+		// The password is the line without its ending, see readPassword.
+		if got := strings.TrimRight(line, "\r\n"); got != strings.TrimRight(tt.line, "\r\n") {
+			t.Errorf("readLine(%q) password %q", tt.in, got)
+		}
+
+		rest, _ := io.ReadAll(r)
+		if string(rest) != tt.rest {
+			t.Errorf("readLine(%q) left %q, want %q", tt.in, rest, tt.rest)
+		}
+	}
+}
+
+// TestSetDefaults checks the context's zone and cluster fill in the flags of
+// object commands, two levels below stack, and only when not given.
+func TestSetDefaults(t *testing.T) {
+	newCmd := func(use string) *cobra.Command {
+		cmd := &cobra.Command{Use: use}
+		cmd.Flags().String("zone", "", "")
+		cmd.Flags().String("cluster", "", "")
+
+		return cmd
+	}
+
+	root := &cobra.Command{Use: "stack"}
+	dump := newCmd("dump")
+	list := &cobra.Command{Use: "list"}
+	host := newCmd("host")
+	nic := newCmd("interface")
+
+	root.AddCommand(dump, list)
+	list.AddCommand(host)
+	host.AddCommand(nic)
+
+	ctx := config.Context{Zone: "east", Cluster: "c1"}
+
+	tests := []struct {
+		cmd           *cobra.Command
+		args          []string
+		zone, cluster string
+	}{
+		{cmd: dump},
+		{cmd: host, zone: "east", cluster: "c1"},
+		{cmd: nic, zone: "east", cluster: "c1"},
+		{cmd: host, args: []string{"--zone", "west"}, zone: "west", cluster: "c1"},
+	}
+
+	for _, tt := range tests {
+		flags := tt.cmd.Flags()
+
+		for _, name := range []string{"zone", "cluster"} {
+			f := flags.Lookup(name)
+			f.Changed = false
+			_ = f.Value.Set("")
+		}
+
+		if err := flags.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := setDefaults(tt.cmd, &ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		zone, _ := flags.GetString("zone")
+		cluster, _ := flags.GetString("cluster")
+
+		if zone != tt.zone || cluster != tt.cluster {
+			t.Errorf("%s %q: zone %q, cluster %q, want %q, %q",
+				tt.cmd.CommandPath(), tt.args, zone, cluster, tt.zone, tt.cluster)
+		}
+	}
+}
+
+// TestBackupsOffline opens the backups of an offline command, which has not
+// connected, from its context.
+func TestBackupsOffline(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	t.Setenv("STACK_CONTEXT", "")
+
+	cfg, err := config.Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Set("lab", "server", "unreachable:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{Use: "list"}
+	for flag := range contextFlags {
+		cmd.Flags().String(flag, "", "")
+	}
+
+	s := session{configFile: cfg.Path(), cmd: cmd}
+
+	store, err := s.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := store.Save([]byte("zones: []\n"), "load")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(dir, "stack", "backups", "lab"); filepath.Dir(snap.Path) != want {
+		t.Errorf("saved %s, want it in %s", snap.Path, want)
+	}
+}